@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// BatchOperation graphql operation of the batch request.
+type BatchOperation struct {
+	// Endpoint the graphql endpoint of the schema, e.g. /users
+	Endpoint string `json:"endpoint"`
+	Query    string `json:"query,omitempty"`
+	// Extensions extensions of the Automatic Persisted Queries protocol
+	Extensions json.RawMessage `json:"extensions,omitempty"`
+}
+
+// batchRequest request of the operation, the headers of the batch request are shared.
+type batchRequest struct {
+	tenantID string
+	userID   string
+	query    string
+	hash     string
+}
+
+// batchEndpoints execute the operation of the endpoint, as the handler of the endpoint does.
+var batchEndpoints = map[string]func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error){
+	"/user": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.SearchUserReq{}
+		req.TenantID, req.Query, req.Hash = r.tenantID, r.query, r.hash
+		resp, err := s.SearchUser(ctx, req)
+		return resp.Users, resp.Result, err
+	},
+	"/users": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.UserByIDsReq{}
+		req.TenantID, req.Query, req.Hash = r.tenantID, r.query, r.hash
+		resp, err := s.UserByIDs(ctx, req)
+		return resp.Users, resp.Result, err
+	},
+	"/department": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.SearchDepartmentReq{}
+		req.TenantID, req.Query, req.Hash = r.tenantID, r.query, r.hash
+		resp, err := s.SearchDepartment(ctx, req)
+		return resp.Departments, resp.Result, err
+	},
+	"/departments": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.DepartmentsByIDsReq{}
+		req.TenantID, req.Query, req.Hash = r.tenantID, r.query, r.hash
+		resp, err := s.DepartmentByIDs(ctx, req)
+		return resp.Departments, resp.Result, err
+	},
+	"/department/member": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.DepartmentMemberReq{}
+		req.TenantID, req.Query, req.Hash = r.tenantID, r.query, r.hash
+		resp, err := s.DepartmentMember(ctx, req)
+		return resp.Users, resp.Result, err
+	},
+	"/subordinate": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.SubordinateReq{}
+		req.UserID, req.TenantID, req.Query, req.Hash = r.userID, r.tenantID, r.query, r.hash
+		resp, err := s.Subordinate(ctx, req)
+		return resp.Users, resp.Result, err
+	},
+	"/leader": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.LeaderReq{}
+		req.UserID, req.TenantID, req.Query, req.Hash = r.userID, r.tenantID, r.query, r.hash
+		resp, err := s.Leader(ctx, req)
+		return resp.Leaders, resp.Result, err
+	},
+	"/role/member": func(ctx context.Context, s *service.Search, r *batchRequest) (interface{}, service.Result, error) {
+		req := &service.RoleMemberReq{}
+		req.TenantID, req.Query, req.Hash = r.tenantID, r.query, r.hash
+		resp, err := s.RoleMember(ctx, req)
+		return resp.Users, resp.Result, err
+	},
+}
+
+// Batch execute the graphql operations of the body concurrently by limits.batchWorkers,
+// the data is the envelopes of the operations in order, each carrying its own error.
+func (r *Router) Batch(c *gin.Context) {
+	ops := []BatchOperation{}
+	if err := c.ShouldBindJSON(&ops); err != nil {
+		write(c, nil, service.Result{}, errdefs.NewInvalidArgument("%s", err.Error()))
+		return
+	}
+	state := r.state()
+	switch limits := state.conf.Limits; {
+	case len(ops) == 0:
+		write(c, nil, service.Result{}, errdefs.NewInvalidArgument("at least one operation is must"))
+		return
+	case len(ops) > limits.MaxBatch:
+		write(c, nil, service.Result{}, errdefs.NewInvalidArgument("at most %d operations are allowed, got %d", limits.MaxBatch, len(ops)))
+		return
+	}
+
+	ctx := mutateContext(c)
+	tenantID, userID := c.GetHeader("Tenant-Id"), c.GetHeader("User-Id")
+	results := make([]*Response, len(ops))
+	indices := make(chan int)
+	workers := state.conf.Limits.BatchWorkers
+	if workers > len(ops) {
+		workers = len(ops)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = r.execute(ctx, state, &ops[i], tenantID, userID)
+			}
+		}()
+	}
+	for i := range ops {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	write(c, results, service.Result{}, nil)
+}
+
+// execute execute the operation with the timeout of its endpoint,
+// the panic of the operation fails the operation only.
+func (r *Router) execute(ctx context.Context, current *state, op *BatchOperation, tenantID, userID string) (resp *Response) {
+	defer func() {
+		if p := recover(); p != nil {
+			util.LoggerWithRequest(ctx, r.log).Info("panic recovered", "panic", p, "endpoint", op.Endpoint)
+			resp, _ = envelope(nil, service.Result{}, errdefs.New(errdefs.Internal, "internal error"))
+		}
+	}()
+
+	fn, ok := batchEndpoints[op.Endpoint]
+	if !ok {
+		resp, _ = envelope(nil, service.Result{}, errdefs.NewInvalidArgument("unknown endpoint %q", op.Endpoint))
+		return resp
+	}
+	req := &batchRequest{
+		tenantID: tenantID,
+		userID:   userID,
+		query:    op.Query,
+	}
+	if len(op.Extensions) > 0 {
+		hash, err := persistedHash(op.Extensions)
+		if err != nil {
+			resp, _ = envelope(nil, service.Result{}, err)
+			return resp
+		}
+		req.hash = hash
+	}
+
+	if d := current.conf.Timeout.Of(op.Endpoint); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	data, result, err := fn(ctx, current.search, req)
+	resp, _ = envelope(data, result, err)
+	return resp
+}
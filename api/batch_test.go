@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+func TestBatch(t *testing.T) {
+	r := newTestRouter(t)
+
+	do := func(body string) (int, *Response, []*Response) {
+		req := httptest.NewRequest(http.MethodPost, basePath+"/batch", strings.NewReader(body))
+		req.Header.Set("Tenant-Id", "t1")
+		req.Header.Set("User-Id", "u3")
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, req)
+
+		results := []*Response{}
+		resp := &Response{Data: &results}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, resp, results
+	}
+
+	ops := []*BatchOperation{
+		{Endpoint: "/user", Query: `{query(name:"Alice"){total users{id}}}`},
+		{Endpoint: "/leader", Query: `{query{id}}`},
+		{Endpoint: "/unknown", Query: `{query{id}}`},
+		{Endpoint: "/users", Query: `{query(ids:["u1","u2"]){total users{id}}}`},
+		{Endpoint: "/department", Query: `{query{total departments{id}} `},
+		{Endpoint: "/departments", Query: `{query(ids:["d1"]){total departments{id}}}`},
+		{Endpoint: "/user", Extensions: json.RawMessage(`{"persistedQuery":{"version":2,"sha256Hash":"x"}}`)},
+	}
+	body, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, resp, results := do(string(body))
+	if status != http.StatusOK || resp.Code != errdefs.Success {
+		t.Fatalf("batch: status %d, code %d, msg %s", status, resp.Code, resp.Msg)
+	}
+	if len(results) != len(ops) {
+		t.Fatalf("batch: %d results of %d operations", len(results), len(ops))
+	}
+
+	codes := []errdefs.Code{
+		errdefs.Success,
+		errdefs.Success,
+		errdefs.InvalidArgument,
+		errdefs.Success,
+		errdefs.InvalidArgument,
+		errdefs.Success,
+		errdefs.InvalidArgument,
+	}
+	for i, result := range results {
+		if result.Code != codes[i] {
+			t.Errorf("operation %d: code %d, expect %d, msg %s", i, result.Code, codes[i], result.Msg)
+		}
+	}
+	total := func(result *Response) float64 {
+		data, _ := result.Data.(map[string]interface{})
+		total, _ := data["total"].(float64)
+		return total
+	}
+	if total(results[0]) != 1 || total(results[3]) != 2 || total(results[5]) != 1 {
+		t.Errorf("batch: unexpected data %v, %v, %v", results[0].Data, results[3].Data, results[5].Data)
+	}
+	if leaders, _ := results[1].Data.([]interface{}); len(leaders) != 2 {
+		t.Errorf("batch: leaders of the user header %v", results[1].Data)
+	}
+	if len(results[4].Errors) == 0 {
+		t.Error("batch: syntax error is missing")
+	}
+
+	for _, body := range []string{`[]`, `{}`} {
+		if status, _, _ := do(body); status != http.StatusBadRequest {
+			t.Errorf("batch %s: status %d", body, status)
+		}
+	}
+	if status, _, results := do(`[null]`); status != http.StatusOK ||
+		len(results) != 1 || results[0].Code != errdefs.InvalidArgument {
+		t.Errorf("batch of null: status %d, results %v", status, results)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i := 0; i <= r.state().conf.Limits.MaxBatch; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(`{"endpoint":"/user","query":"{query{total}}"}`)
+	}
+	buf.WriteString("]")
+	if status, _, _ := do(buf.String()); status != http.StatusBadRequest {
+		t.Errorf("batch over the limit: status %d", status)
+	}
+}
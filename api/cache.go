@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/search/internal/models/cache"
+	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+// InvalidateCacheReq invalidate the cached results of the tenant,
+// sent by the writers after the documents are indexed.
+type InvalidateCacheReq struct {
+	// Kind user or department, empty for both
+	Kind     string `json:"kind"`
+	TenantID string `json:"tenantID"`
+}
+
+// InvalidateCache invalidation hook of the index writes, the writers are
+// authorized by the bearer token of cache.invalidateToken. The stale snapshot
+// of the tenant is dropped too.
+func (r *Router) InvalidateCache(c *gin.Context) {
+	state := r.state()
+	if err := authorizeInvalidate(c, state.conf.Cache.InvalidateToken); err != nil {
+		write(c, nil, service.Result{}, err)
+		return
+	}
+
+	req := &InvalidateCacheReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		write(c, nil, service.Result{}, errdefs.NewInvalidArgument("%s", err.Error()))
+		return
+	}
+
+	kinds := []string{cache.KindUser, cache.KindDepartment}
+	switch req.Kind {
+	case "":
+	case cache.KindUser, cache.KindDepartment:
+		kinds = []string{req.Kind}
+	default:
+		write(c, nil, service.Result{}, errdefs.NewInvalidArgument("unknown kind %q", req.Kind))
+		return
+	}
+
+	var invalidators []func(ctx context.Context, kind, tenantID string) error
+	if state.stale != nil {
+		invalidators = append(invalidators, state.stale.Invalidate)
+	}
+	if state.cache != nil {
+		invalidators = append(invalidators, state.cache.Invalidate)
+	}
+	ctx := mutateContext(c)
+	for _, invalidate := range invalidators {
+		for _, kind := range kinds {
+			if err := invalidate(ctx, kind, req.TenantID); err != nil {
+				write(c, nil, service.Result{}, errdefs.Wrap(errdefs.Unavailable, err))
+				return
+			}
+		}
+	}
+	write(c, nil, service.Result{}, nil)
+}
+
+// authorizeInvalidate check the bearer token of the request against token,
+// every request is rejected if token is empty.
+func authorizeInvalidate(c *gin.Context, token string) error {
+	if token == "" {
+		return errdefs.NewUnauthorized("cache invalidation is disabled, cache.invalidateToken is not set")
+	}
+	const prefix = "Bearer "
+	auth := c.GetHeader("Authorization")
+	if len(auth) <= len(prefix) || auth[:len(prefix)] != prefix ||
+		subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(token)) != 1 {
+		return errdefs.NewUnauthorized("invalid token")
+	}
+	return nil
+}
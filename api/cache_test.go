@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/quanxiang-cloud/search/internal/config"
+)
+
+func TestInvalidateCache(t *testing.T) {
+	do := func(r *Router, auth string) int {
+		req := httptest.NewRequest(http.MethodPost, basePath+"/cache/invalidate",
+			strings.NewReader(`{"kind":"user","tenantID":"t1"}`))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.Handler().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	disabled := newTestRouter(t)
+	if code := do(disabled, "Bearer "); code != http.StatusUnauthorized {
+		t.Errorf("without token config: status %d", code)
+	}
+
+	r := newTestRouter(t, func(conf *config.Config) {
+		conf.Cache.Backend = config.CacheMemory
+		conf.Cache.InvalidateToken = "s3cret"
+	})
+	tests := []struct {
+		auth   string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"s3cret", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := do(r, tt.auth); code != tt.status {
+			t.Errorf("authorization %q: status %d, expect %d", tt.auth, code, tt.status)
+		}
+	}
+}
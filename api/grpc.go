@@ -0,0 +1,361 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1/searchpb"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadata keys of the grpc requests, the lowercase of the http headers.
+var (
+	mdRequestID = strings.ToLower(header.RequestID)
+	mdTenantID  = "tenant-id"
+	mdUserID    = "user-id"
+)
+
+// newGRPCServer return the grpc server of the typed search,
+// sharing the search service and the timeouts of the router.
+func newGRPCServer(r *Router) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		grpcRequest(r.log),
+		tracing.UnaryServerInterceptor(),
+		metrics.UnaryServerInterceptor(),
+		grpcRecovery(),
+		grpcTimeout(r),
+	))
+	searchpb.RegisterSearchServer(server, &searchServer{
+		current: func() *service.Search {
+			return r.state().search
+		},
+	})
+	return server
+}
+
+// grpcRequest make sure every request carries a request id, and attach
+// request id, tenant and user of the metadata to the logger like requestLogger.
+func grpcRequest(log logr.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		rid := mdValue(ctx, mdRequestID)
+		if rid == "" {
+			rid = id.StringUUID()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(mdRequestID, rid))
+
+		var _requestID interface{} = header.RequestID
+		ctx = context.WithValue(ctx, _requestID, rid)
+		ctx = util.SetRequestValues(ctx,
+			header.RequestID, rid,
+			"tenantID", mdValue(ctx, mdTenantID),
+			"userID", mdValue(ctx, mdUserID),
+			"method", info.FullMethod,
+		)
+		ctx = util.SetCtx(ctx, util.ContextKey{}, util.LoggerWithRequest(ctx, log))
+		return handler(ctx, req)
+	}
+}
+
+// grpcRecovery turn the panic of the handler into an internal error.
+func grpcRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				util.LoggerFromContext(ctx).Info("panic recovered", "panic", p)
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// grpcTimeout set the deadline of the request by the current config,
+// the full method name is the endpoint, e.g. /search.v1alpha1.Search/SearchUsers.
+func grpcTimeout(r *Router) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if d := r.state().conf.Timeout.Of(info.FullMethod); d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+func mdValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type searchServer struct {
+	searchpb.UnimplementedSearchServer
+	// current return the current search service, which is swapped by reload
+	current func() *service.Search
+}
+
+func (s *searchServer) SearchUsers(ctx context.Context, req *searchpb.SearchUsersRequest) (*searchpb.UsersResponse, error) {
+	query, err := fromSearchUser(ctx, req.GetQuery())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	page, size := fromPage(req.GetPage())
+	resp, err := s.current().QueryUsers(ctx, query, page, size)
+	return usersResponse(resp, err)
+}
+
+func (s *searchServer) GetUsersByIDs(ctx context.Context, req *searchpb.GetUsersByIDsRequest) (*searchpb.UsersResponse, error) {
+	tenantID, err := tenantID(ctx, req.GetTenantId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp, err := s.current().QueryUsersByIDs(ctx, tenantID, req.GetIds())
+	return usersResponse(resp, err)
+}
+
+func (s *searchServer) DepartmentMembers(ctx context.Context, req *searchpb.DepartmentMembersRequest) (*searchpb.UsersResponse, error) {
+	if req.GetDepartmentId() == "" {
+		return nil, grpcError(errdefs.NewInvalidArgument("departmentID is must"))
+	}
+	query, err := fromSearchUser(ctx, req.GetQuery())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	query.DepartmentID = req.GetDepartmentId()
+	page, size := fromPage(req.GetPage())
+	resp, err := s.current().QueryUsers(ctx, query, page, size)
+	return usersResponse(resp, err)
+}
+
+func (s *searchServer) Subordinates(ctx context.Context, req *searchpb.SubordinatesRequest) (*searchpb.UsersResponse, error) {
+	userID := req.GetUserId()
+	if userID == "" {
+		userID = mdValue(ctx, mdUserID)
+	}
+	if userID == "" {
+		return nil, grpcError(errdefs.NewInvalidArgument("userID is must"))
+	}
+	query, err := fromSearchUser(ctx, req.GetQuery())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	query.LeaderID = userID
+	page, size := fromPage(req.GetPage())
+	resp, err := s.current().QueryUsers(ctx, query, page, size)
+	return usersResponse(resp, err)
+}
+
+func (s *searchServer) Leaders(ctx context.Context, req *searchpb.LeadersRequest) (*searchpb.UsersResponse, error) {
+	userID := req.GetUserId()
+	if userID == "" {
+		userID = mdValue(ctx, mdUserID)
+	}
+	if userID == "" {
+		return nil, grpcError(errdefs.NewInvalidArgument("userID is must"))
+	}
+	tenantID, err := tenantID(ctx, req.GetTenantId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp, err := s.current().QueryLeaders(ctx, tenantID, userID)
+	return usersResponse(resp, err)
+}
+
+func (s *searchServer) RoleMembers(ctx context.Context, req *searchpb.RoleMembersRequest) (*searchpb.UsersResponse, error) {
+	if req.GetRoleId() == "" {
+		return nil, grpcError(errdefs.NewInvalidArgument("roleID is must"))
+	}
+	query, err := fromSearchUser(ctx, req.GetQuery())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	query.RoleID = req.GetRoleId()
+	page, size := fromPage(req.GetPage())
+	resp, err := s.current().QueryUsers(ctx, query, page, size)
+	return usersResponse(resp, err)
+}
+
+func (s *searchServer) SearchDepartments(ctx context.Context, req *searchpb.SearchDepartmentsRequest) (*searchpb.DepartmentsResponse, error) {
+	query, err := fromSearchDepartment(ctx, req.GetQuery())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	page, size := fromPage(req.GetPage())
+	resp, err := s.current().QueryDepartments(ctx, query, page, size)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	deps := make([]*searchpb.Department, 0, len(resp.Departments))
+	for _, dep := range resp.Departments {
+		deps = append(deps, toDepartment(*dep))
+	}
+	return &searchpb.DepartmentsResponse{
+		Departments: deps,
+		Total:       resp.Total,
+		Partial:     resp.Partial,
+		Stale:       resp.Stale,
+	}, nil
+}
+
+// grpcError return the status error of err, the code is decided by the code of err.
+func grpcError(err error) error {
+	e := errdefs.From(err)
+	code := codes.Unknown
+	switch e.Code {
+	case errdefs.InvalidArgument:
+		code = codes.InvalidArgument
+	case errdefs.NotFound:
+		code = codes.NotFound
+	case errdefs.Unauthorized:
+		code = codes.Unauthenticated
+	case errdefs.Unavailable:
+		code = codes.Unavailable
+	case errdefs.Timeout:
+		code = codes.DeadlineExceeded
+	case errdefs.Internal:
+		code = codes.Internal
+	}
+	return status.Error(code, e.Error())
+}
+
+// tenantID return the tenant of the metadata, set by the gateway like the Tenant-Id header
+// of the restful api. The tenant of the request never overrides it, it must be empty or the same.
+func tenantID(ctx context.Context, requested string) (string, error) {
+	tenantID := mdValue(ctx, mdTenantID)
+	if requested != "" && requested != tenantID {
+		return "", errdefs.NewInvalidArgument("tenantID %q differs from the tenant of the metadata", requested)
+	}
+	return tenantID, nil
+}
+
+func fromPage(page *searchpb.Page) (int, int) {
+	return int(page.GetPage()), int(page.GetSize())
+}
+
+func fromSearchUser(ctx context.Context, q *searchpb.SearchUser) (*v1alpha1.SearchUser, error) {
+	tenantID, err := tenantID(ctx, q.GetTenantId())
+	if err != nil {
+		return nil, err
+	}
+	return &v1alpha1.SearchUser{
+		TenantID:       tenantID,
+		Name:           q.GetName(),
+		Phone:          q.GetPhone(),
+		Email:          q.GetEmail(),
+		JobNumber:      q.GetJobNumber(),
+		Gender:         q.GetGender(),
+		UseStatus:      int(q.GetUseStatus()),
+		DepartmentName: q.GetDepartmentName(),
+		DepartmentID:   q.GetDepartmentId(),
+		RoleID:         q.GetRoleId(),
+		RoleName:       q.GetRoleName(),
+		LeaderID:       q.GetLeaderId(),
+		OrderBy:        q.GetOrderBy(),
+		Position:       q.GetPosition(),
+	}, nil
+}
+
+func fromSearchDepartment(ctx context.Context, q *searchpb.SearchDepartment) (*v1alpha1.SearchDepartment, error) {
+	tenantID, err := tenantID(ctx, q.GetTenantId())
+	if err != nil {
+		return nil, err
+	}
+	query := &v1alpha1.SearchDepartment{
+		TenantID: tenantID,
+		Name:     q.GetName(),
+		OrderBy:  q.GetOrderBy(),
+	}
+	for _, attr := range q.GetAttr() {
+		query.Attr = append(query.Attr, int(attr))
+	}
+	for _, id := range q.GetIds() {
+		query.IDS = append(query.IDS, id)
+	}
+	return query, nil
+}
+
+func usersResponse(resp *service.UserPage, err error) (*searchpb.UsersResponse, error) {
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	users := make([]*searchpb.User, 0, len(resp.Users))
+	for _, user := range resp.Users {
+		users = append(users, toUser(user))
+	}
+	return &searchpb.UsersResponse{
+		Users:   users,
+		Total:   resp.Total,
+		Partial: resp.Partial,
+		Stale:   resp.Stale,
+	}, nil
+}
+
+func toUser(u *v1alpha1.User) *searchpb.User {
+	user := &searchpb.User{
+		Id:        u.ID,
+		Name:      u.Name,
+		Phone:     u.Phone,
+		Email:     u.Email,
+		CreatedAt: u.CreatedAt,
+		JobNumber: u.JobNumber,
+		Avatar:    u.Avatar,
+		UseStatus: int32(u.UseStatus),
+		TenantId:  u.TenantID,
+		Gender:    int32(u.Gender),
+		Source:    u.Source,
+		SelfEmail: u.SelfEmail,
+		Position:  u.Position,
+	}
+	for _, path := range u.Departments {
+		deps := &searchpb.DepartmentPath{}
+		for _, dep := range path {
+			deps.Departments = append(deps.Departments, toDepartment(dep))
+		}
+		user.Departments = append(user.Departments, deps)
+	}
+	for _, path := range u.Leaders {
+		leaders := &searchpb.LeaderPath{}
+		for _, leader := range path {
+			leaders.Leaders = append(leaders.Leaders, &searchpb.Leader{
+				Id:   leader.ID,
+				Name: leader.Name,
+				Attr: leader.Attr,
+			})
+		}
+		user.Leaders = append(user.Leaders, leaders)
+	}
+	for _, role := range u.Roles {
+		user.Roles = append(user.Roles, &searchpb.Role{
+			Id:   role.ID,
+			Name: role.Name,
+		})
+	}
+	return user
+}
+
+func toDepartment(dep v1alpha1.Department) *searchpb.Department {
+	return &searchpb.Department{
+		Id:       dep.ID,
+		Name:     dep.Name,
+		Pid:      dep.PID,
+		Attr:     dep.Attr,
+		TenantId: dep.TenantID,
+	}
+}
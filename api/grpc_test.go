@@ -0,0 +1,336 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1/searchpb"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient return the client of the grpc server of the test router, served in memory.
+func newTestClient(t *testing.T) searchpb.SearchClient {
+	t.Helper()
+	server := newGRPCServer(newTestRouter(t))
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return searchpb.NewSearchClient(conn)
+}
+
+func withMetadata(kv ...string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(kv...))
+}
+
+func pbUserIDs(users []*searchpb.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.GetId())
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGRPCServer(t *testing.T) {
+	client := newTestClient(t)
+	ctx := withMetadata(mdTenantID, "t1", mdUserID, "u3")
+
+	var header metadata.MD
+	users, err := client.SearchUsers(ctx, &searchpb.SearchUsersRequest{
+		Query: &searchpb.SearchUser{OrderBy: []string{"-createdAt"}},
+		Page:  &searchpb.Page{Page: 1, Size: 2},
+	}, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.GetTotal() != 4 || !equalIDs(pbUserIDs(users.GetUsers()), []string{"u4", "u3"}) {
+		t.Errorf("search users: total %d, users %v", users.GetTotal(), pbUserIDs(users.GetUsers()))
+	}
+	if len(header.Get(mdRequestID)) == 0 {
+		t.Error("search users: request id is missing")
+	}
+
+	tests := []struct {
+		name  string
+		call  func() (*searchpb.UsersResponse, error)
+		total int64
+		ids   []string
+	}{{
+		name: "users by ids",
+		call: func() (*searchpb.UsersResponse, error) {
+			return client.GetUsersByIDs(ctx, &searchpb.GetUsersByIDsRequest{Ids: []string{"u2", "u3"}})
+		},
+		ids: []string{"u2", "u3"},
+	}, {
+		name: "department members",
+		call: func() (*searchpb.UsersResponse, error) {
+			return client.DepartmentMembers(ctx, &searchpb.DepartmentMembersRequest{DepartmentId: "d2"})
+		},
+		total: 2,
+	}, {
+		name: "role members",
+		call: func() (*searchpb.UsersResponse, error) {
+			return client.RoleMembers(ctx, &searchpb.RoleMembersRequest{RoleId: "r1"})
+		},
+		total: 2,
+	}, {
+		name: "subordinates of the request",
+		call: func() (*searchpb.UsersResponse, error) {
+			return client.Subordinates(ctx, &searchpb.SubordinatesRequest{UserId: "u1"})
+		},
+		total: 3,
+	}, {
+		name: "leaders of the metadata",
+		call: func() (*searchpb.UsersResponse, error) {
+			return client.Leaders(ctx, &searchpb.LeadersRequest{})
+		},
+		ids: []string{"u2", "u1"},
+	}}
+	for _, tt := range tests {
+		resp, err := tt.call()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.ids != nil && len(resp.GetUsers()) != len(tt.ids) {
+			t.Errorf("%s: users %v, expect %v", tt.name, pbUserIDs(resp.GetUsers()), tt.ids)
+		}
+		if tt.ids == nil && resp.GetTotal() != tt.total {
+			t.Errorf("%s: total %d, expect %d", tt.name, resp.GetTotal(), tt.total)
+		}
+	}
+
+	deps, err := client.SearchDepartments(ctx, &searchpb.SearchDepartmentsRequest{
+		Query: &searchpb.SearchDepartment{Attr: []int32{1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps.GetTotal() != 1 || deps.GetDepartments()[0].GetId() != "d1" || deps.GetDepartments()[0].GetTenantId() != "t1" {
+		t.Errorf("search departments: %v", deps)
+	}
+}
+
+func TestGRPCTenant(t *testing.T) {
+	client := newTestClient(t)
+
+	users, err := client.SearchUsers(withMetadata(mdTenantID, "t2"), &searchpb.SearchUsersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalIDs(pbUserIDs(users.GetUsers()), []string{"u5"}) {
+		t.Errorf("tenant of the metadata: %v", pbUserIDs(users.GetUsers()))
+	}
+
+	users, err = client.SearchUsers(withMetadata(mdTenantID, "t2"), &searchpb.SearchUsersRequest{
+		Query: &searchpb.SearchUser{TenantId: "t2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalIDs(pbUserIDs(users.GetUsers()), []string{"u5"}) {
+		t.Errorf("same tenant of the request: %v", pbUserIDs(users.GetUsers()))
+	}
+
+	// the tenant of the request never reads another tenant.
+	ctx := withMetadata(mdTenantID, "t2", mdUserID, "u3")
+	calls := map[string]func() error{
+		"search users": func() error {
+			_, err := client.SearchUsers(ctx, &searchpb.SearchUsersRequest{
+				Query: &searchpb.SearchUser{TenantId: "t1"},
+			})
+			return err
+		},
+		"users by ids": func() error {
+			_, err := client.GetUsersByIDs(ctx, &searchpb.GetUsersByIDsRequest{TenantId: "t1", Ids: []string{"u1"}})
+			return err
+		},
+		"department members": func() error {
+			_, err := client.DepartmentMembers(ctx, &searchpb.DepartmentMembersRequest{
+				DepartmentId: "d1",
+				Query:        &searchpb.SearchUser{TenantId: "t1"},
+			})
+			return err
+		},
+		"leaders": func() error {
+			_, err := client.Leaders(ctx, &searchpb.LeadersRequest{TenantId: "t1"})
+			return err
+		},
+		"search departments": func() error {
+			_, err := client.SearchDepartments(ctx, &searchpb.SearchDepartmentsRequest{
+				Query: &searchpb.SearchDepartment{TenantId: "t1"},
+			})
+			return err
+		},
+		"tenant without metadata": func() error {
+			_, err := client.SearchUsers(context.Background(), &searchpb.SearchUsersRequest{
+				Query: &searchpb.SearchUser{TenantId: "t1"},
+			})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if code := status.Code(call()); code != codes.InvalidArgument {
+			t.Errorf("%s: code %s, expect %s", name, code, codes.InvalidArgument)
+		}
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := newTestClient(t)
+	ctx := withMetadata(mdTenantID, "t1")
+
+	_, err := client.DepartmentMembers(ctx, &searchpb.DepartmentMembersRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("department members without department: %v", err)
+	}
+	_, err = client.Leaders(ctx, &searchpb.LeadersRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("leaders without user: %v", err)
+	}
+	_, err = client.Leaders(ctx, &searchpb.LeadersRequest{UserId: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("leaders of unknown user: %v", err)
+	}
+}
+
+func TestGRPCTracing(t *testing.T) {
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagator) })
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"traceparent", "00-"+traceID+"-00f067aa0ba902b7-01",
+	))
+	var got trace.SpanContext
+	_, err := tracing.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/search.Search/Users"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			got = trace.SpanContextFromContext(ctx)
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TraceID().String() != traceID {
+		t.Errorf("trace id %s, expect %s from the metadata", got.TraceID(), traceID)
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{errdefs.NewInvalidArgument("invalid"), codes.InvalidArgument},
+		{errdefs.NewNotFound("not found"), codes.NotFound},
+		{errdefs.New(errdefs.Unauthorized, "unauthorized"), codes.Unauthenticated},
+		{errdefs.NewUnavailable("unavailable"), codes.Unavailable},
+		{errdefs.New(errdefs.Timeout, "timeout"), codes.DeadlineExceeded},
+		{errdefs.New(errdefs.Internal, "internal"), codes.Internal},
+		{errors.New("plain"), codes.Internal},
+		{errdefs.New(errdefs.Unknown, "unknown"), codes.Unknown},
+	}
+	for _, tt := range tests {
+		if code := status.Code(grpcError(tt.err)); code != tt.code {
+			t.Errorf("%v: code %s, expect %s", tt.err, code, tt.code)
+		}
+	}
+}
+
+func TestConverters(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(mdTenantID, "t1"))
+
+	user, err := fromSearchUser(ctx, &searchpb.SearchUser{
+		Name:      "zhang",
+		Gender:    "2",
+		UseStatus: 1,
+		OrderBy:   []string{"-createdAt"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.TenantID != "t1" || user.Name != "zhang" || user.Gender != "2" || user.UseStatus != 1 || user.OrderBy[0] != "-createdAt" {
+		t.Errorf("from search user: %+v", user)
+	}
+	if user, err = fromSearchUser(ctx, nil); err != nil || user.TenantID != "t1" {
+		t.Errorf("from nil search user: %+v, %v", user, err)
+	}
+
+	dep, err := fromSearchDepartment(ctx, &searchpb.SearchDepartment{
+		Attr: []int32{1, 2},
+		Ids:  []string{"d1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dep.TenantID != "t1" || len(dep.Attr) != 2 || dep.Attr[1] != 2 || len(dep.IDS) != 1 {
+		t.Errorf("from search department: %+v", dep)
+	}
+	if _, err := fromSearchDepartment(ctx, &searchpb.SearchDepartment{TenantId: "t2"}); errdefs.From(err).Code != errdefs.InvalidArgument {
+		t.Errorf("from search department of another tenant: %v", err)
+	}
+
+	pb := toUser(&v1alpha1.User{
+		ID:        "u1",
+		Name:      "Alice",
+		UseStatus: 1,
+		Gender:    2,
+		TenantID:  "t1",
+		Departments: [][]v1alpha1.Department{{
+			{ID: "d2", PID: "d1", Attr: "2"},
+			{ID: "d1", Attr: "1"},
+		}},
+		Leaders: [][]v1alpha1.Leader{{{ID: "u2", Name: "Bob", Attr: "x"}}},
+		Roles:   []v1alpha1.Role{{ID: "r1", Name: "admin"}},
+	})
+	if pb.GetId() != "u1" || pb.GetUseStatus() != 1 || pb.GetGender() != 2 || pb.GetTenantId() != "t1" {
+		t.Errorf("to user: %v", pb)
+	}
+	if paths := pb.GetDepartments(); len(paths) != 1 || len(paths[0].GetDepartments()) != 2 ||
+		paths[0].GetDepartments()[0].GetPid() != "d1" || paths[0].GetDepartments()[1].GetAttr() != "1" {
+		t.Errorf("to user departments: %v", paths)
+	}
+	if paths := pb.GetLeaders(); len(paths) != 1 || paths[0].GetLeaders()[0].GetName() != "Bob" {
+		t.Errorf("to user leaders: %v", paths)
+	}
+	if roles := pb.GetRoles(); len(roles) != 1 || roles[0].GetName() != "admin" {
+		t.Errorf("to user roles: %v", roles)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/cabin/id"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// requestID make sure every request carries a Request-Id.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rid := c.GetHeader(header.RequestID)
+		if rid == "" {
+			rid = id.StringUUID()
+			c.Request.Header.Set(header.RequestID, rid)
+		}
+		c.Header(header.RequestID, rid)
+		c.Next()
+	}
+}
+
+// requestLogger attach request id, tenant and user of the request
+// to the logger in the request context.
+func requestLogger(log logr.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := util.SetRequestValues(c.Request.Context(),
+			header.RequestID, c.GetHeader(header.RequestID),
+			"tenantID", c.GetHeader("Tenant-Id"),
+			"userID", c.GetHeader("User-Id"),
+		)
+		ctx = util.SetCtx(ctx, util.ContextKey{}, util.LoggerWithRequest(ctx, log))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// timeout set the deadline of the request context,
+// of return the current timeout, zero means no deadline.
+func timeout(of func() time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := of()
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/search/internal/openapi"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// basePath path prefix of the search endpoints.
+const basePath = "/api/v1/search"
+
+// graphqlEndpoint graphql search endpoint described by the document.
+type graphqlEndpoint struct {
+	path        string
+	operationID string
+	summary     string
+	// user true if the endpoint is about the user of the User-Id header
+	user bool
+	// data the data of the query field
+	data interface{}
+}
+
+var graphqlEndpoints = []graphqlEndpoint{
+	{"/user", "searchUser", "Search the users of the tenant.", false, &userList{}},
+	{"/users", "userByIDs", "List the users of the ids.", false, &userList{}},
+	{"/department", "searchDepartment", "Search the departments of the tenant.", false, &departmentList{}},
+	{"/departments", "departmentsByIDs", "List the departments of the ids.", false, &departmentList{}},
+	{"/department/member", "departmentMember", "Search the members of the department.", false, &userList{}},
+	{"/subordinate", "subordinate", "Search the subordinates of the user.", true, &userList{}},
+	{"/leader", "leader", "List the leaders of the user, from the recent leader to the top leader.", true, []*v1alpha1.User{}},
+	{"/role/member", "roleMember", "Search the members of the role.", false, &userList{}},
+}
+
+// newOpenAPI return the OpenAPI document of the routes of the router,
+// every route registered by NewRouter must be added here.
+func newOpenAPI() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "search",
+		Description: "Search of the users and departments of the tenants.",
+		Version:     "v1",
+	})
+
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"invalidateToken": {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "Token of cache.invalidateToken shared with the writers.",
+		},
+	}
+
+	for _, endpoint := range graphqlEndpoints {
+		params := append(graphqlParameters(), tenantHeader())
+		if endpoint.user {
+			params = append(params, userHeader())
+		}
+		doc.Add(http.MethodGet, basePath+endpoint.path, &openapi.Operation{
+			OperationID: endpoint.operationID,
+			Summary:     endpoint.summary,
+			Description: "The data is the value of the query field, carrying the selected fields only.",
+			Tags:        []string{"graphql"},
+			Parameters:  params,
+			Responses:   responses(doc, endpoint.data),
+		})
+	}
+
+	doc.Add(http.MethodGet, basePath+"/rest/users", &openapi.Operation{
+		OperationID: "restUsers",
+		Summary:     "Search the users by the query parameters.",
+		Tags:        []string{"rest"},
+		Parameters: append(restParameters(doc, &v1alpha1.SearchUser{}),
+			tenantHeader()),
+		Responses: responses(doc, &userList{}),
+	})
+	doc.Add(http.MethodGet, basePath+"/rest/departments", &openapi.Operation{
+		OperationID: "restDepartments",
+		Summary:     "Search the departments by the query parameters.",
+		Tags:        []string{"rest"},
+		Parameters: append(restParameters(doc, &v1alpha1.SearchDepartment{}),
+			tenantHeader()),
+		Responses: responses(doc, &departmentList{}),
+	})
+
+	doc.Add(http.MethodPost, basePath+"/batch", &openapi.Operation{
+		OperationID: "batch",
+		Summary:     "Execute the graphql operations of the endpoints concurrently.",
+		Description: "The data is the envelopes of the operations in order, each carrying its own error. " +
+			"The headers are shared by the operations, at most limits.maxBatch operations are allowed.",
+		Tags:       []string{"graphql"},
+		Parameters: []*openapi.Parameter{tenantHeader(), userHeader()},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  jsonContent(doc.Schema([]BatchOperation{})),
+		},
+		Responses: responses(doc, []*Response{}),
+	})
+	doc.Add(http.MethodPost, basePath+"/cache/invalidate", &openapi.Operation{
+		OperationID: "invalidateCache",
+		Summary:     "Invalidate the cached results of the tenant, sent by the writers after the documents are indexed.",
+		Description: "The writers are authorized by the bearer token of cache.invalidateToken, the hook is disabled if it is empty.",
+		Tags:        []string{"cache"},
+		Security:    []map[string][]string{{"invalidateToken": {}}},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  jsonContent(doc.Schema(&InvalidateCacheReq{})),
+		},
+		Responses: responses(doc, nil),
+	})
+	doc.Add(http.MethodGet, basePath+"/openapi.json", &openapi.Operation{
+		OperationID: "openapi",
+		Summary:     "This document.",
+		Tags:        []string{"meta"},
+		Responses: map[string]*openapi.Response{
+			"200": {
+				Description: "The OpenAPI document.",
+				Content:     jsonContent(&openapi.Schema{Type: "object"}),
+			},
+		},
+	})
+
+	doc.Add(http.MethodGet, "/liveness", &openapi.Operation{
+		OperationID: "liveness",
+		Summary:     "Liveness probe.",
+		Tags:        []string{"meta"},
+		Responses:   probeResponses(),
+	})
+	// readiness accepts any method
+	for _, method := range anyMethods {
+		id := "readiness"
+		if method != http.MethodGet {
+			id += method[:1] + strings.ToLower(method[1:])
+		}
+		doc.Add(method, "/readiness", &openapi.Operation{
+			OperationID: id,
+			Summary:     "Readiness probe, failed while the health checks fail.",
+			Tags:        []string{"meta"},
+			Responses:   probeResponses(),
+		})
+	}
+	doc.Add(http.MethodGet, "/metrics", &openapi.Operation{
+		OperationID: "metrics",
+		Summary:     "Prometheus metrics.",
+		Tags:        []string{"meta"},
+		Responses: map[string]*openapi.Response{
+			"200": {
+				Description: "The metrics in the Prometheus text format.",
+				Content: map[string]*openapi.MediaType{
+					"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+				},
+			},
+		},
+	})
+	return doc
+}
+
+// anyMethods methods registered by gin Any.
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+	http.MethodTrace,
+}
+
+func graphqlParameters() []*openapi.Parameter {
+	return []*openapi.Parameter{{
+		Name:        "query",
+		In:          "query",
+		Description: `GraphQL query, e.g. {query(name:"zhang",page:1,size:10){total users{id name}}}, optional if the persisted query is registered.`,
+		Schema:      &openapi.Schema{Type: "string"},
+	}, {
+		Name:        "extensions",
+		In:          "query",
+		Description: `Automatic Persisted Queries extensions, e.g. {"persistedQuery":{"version":1,"sha256Hash":"..."}}.`,
+		Schema:      &openapi.Schema{Type: "string"},
+	}}
+}
+
+// restParameters return the query parameters of the filter and the page.
+func restParameters(doc *openapi.Document, filter interface{}) []*openapi.Parameter {
+	params := append(doc.Parameters(filter), doc.Parameters(&restPage{})...)
+	for _, param := range params {
+		switch param.Name {
+		case "orderBy":
+			param.Description = "Sort fields, descending if prefixed by -, e.g. -createdAt."
+		case "size":
+			param.Description = "Page size between 0 and 999, 0 means 999."
+		}
+	}
+	return params
+}
+
+func tenantHeader() *openapi.Parameter {
+	return &openapi.Parameter{
+		Name:        "Tenant-Id",
+		In:          "header",
+		Description: "Tenant of the request.",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+func userHeader() *openapi.Parameter {
+	return &openapi.Parameter{
+		Name:        "User-Id",
+		In:          "header",
+		Description: "User of the request.",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+// responses return the responses of the envelope carrying data, nil for no data.
+func responses(doc *openapi.Document, data interface{}) map[string]*openapi.Response {
+	envelope := doc.Schema(&Response{})
+	ok := envelope
+	if data != nil {
+		ok = &openapi.Schema{
+			AllOf: []*openapi.Schema{envelope, {
+				Type: "object",
+				Properties: map[string]*openapi.Schema{
+					"data": doc.Schema(data),
+				},
+			}},
+		}
+	}
+	return map[string]*openapi.Response{
+		"200": {
+			Description: "The data of the envelope, partial or stale flagged if degraded.",
+			Content:     jsonContent(ok),
+		},
+		"default": {
+			Description: "The error of the envelope, the status is decided by the code.",
+			Content:     jsonContent(envelope),
+		},
+	}
+}
+
+func probeResponses() map[string]*openapi.Response {
+	return map[string]*openapi.Response{
+		"200": {Description: "Healthy."},
+		"400": {Description: "Unhealthy."},
+	}
+}
+
+func jsonContent(schema *openapi.Schema) map[string]*openapi.MediaType {
+	return map[string]*openapi.MediaType{
+		"application/json": {Schema: schema},
+	}
+}
+
+// serveOpenAPI serve the document.
+func serveOpenAPI(doc *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/search/internal/config"
+	"github.com/quanxiang-cloud/search/internal/openapi"
+)
+
+// newTestRouter return the router on the memory storage loaded with the fixtures
+// of the service tests, opts change the config before the router is built.
+func newTestRouter(t *testing.T, opts ...func(conf *config.Config)) *Router {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	conf := config.Default()
+	conf.Storage.Backend = config.StorageMemory
+	conf.Storage.Memory.Fixtures = "../internal/service/testdata/fixtures.json"
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	r, err := NewRouter(context.Background(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	return r
+}
+
+// TestOpenAPICoverage fail if a route is registered without the entry of the document.
+func TestOpenAPICoverage(t *testing.T) {
+	r := newTestRouter(t)
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, basePath+"/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("serve document: status %d", w.Code)
+	}
+	doc := &openapi.Document{}
+	if err := json.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range r.router.Routes() {
+		if !openapi.Supported(route.Method) {
+			continue
+		}
+		if doc.Operation(route.Method, route.Path) == nil {
+			t.Errorf("%s %s is registered without the entry of the OpenAPI document", route.Method, route.Path)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := newOpenAPI()
+	body, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every reference resolves
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := doc.Components.Schemas[name]; !ok {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, value := range v {
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatal(err)
+	}
+	walk(raw)
+
+	ids := make(map[string]string)
+	for path, item := range doc.Paths {
+		for _, op := range []*openapi.Operation{item.Get, item.Put, item.Post, item.Delete,
+			item.Options, item.Head, item.Patch, item.Trace} {
+			if op == nil {
+				continue
+			}
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("operation id %s of %s is used by %s", op.OperationID, path, other)
+			}
+			ids[op.OperationID] = path
+		}
+	}
+
+	params := doc.Operation(http.MethodGet, basePath+"/rest/users").Parameters
+	names := make(map[string]bool)
+	for _, param := range params {
+		names[param.Name] = true
+	}
+	for _, name := range []string{"name", "departmentID", "orderBy", "page", "size", "Tenant-Id"} {
+		if !names[name] {
+			t.Errorf("rest users: parameter %s is missing", name)
+		}
+	}
+	if names["tenantID"] {
+		t.Error("rest users: tenantID is bound from the header only")
+	}
+}
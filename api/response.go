@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+// Response response envelope of all search endpoints.
+type Response struct {
+	Code      errdefs.Code               `json:"code"`
+	Msg       string                     `json:"msg,omitempty"`
+	Data      interface{}                `json:"data,omitempty"`
+	RequestID string                     `json:"requestID,omitempty"`
+	Errors    []gqlerrors.FormattedError `json:"errors,omitempty"`
+	// Partial true if the data is incomplete.
+	Partial bool `json:"partial,omitempty"`
+	// Stale true if the data is the last known good one served while the storage is down.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// write write the envelope, the http status is decided by the code of err,
+// errors of the partial data are returned along with the data.
+func write(c *gin.Context, data interface{}, result service.Result, err error) {
+	resp, status := envelope(data, result, err)
+	resp.RequestID = c.GetHeader(header.RequestID)
+	c.Render(status, negotiate(c, resp))
+}
+
+// envelope return the envelope without the request id and the http status of it.
+func envelope(data interface{}, result service.Result, err error) (*Response, int) {
+	resp := &Response{
+		Code:    errdefs.Success,
+		Errors:  result.Errors,
+		Partial: result.Partial,
+		Stale:   result.Stale,
+	}
+
+	status := errdefs.Success.HTTPStatus()
+	if err != nil {
+		e := errdefs.From(err)
+		resp.Code = e.Code
+		resp.Msg = e.Error()
+		status = e.Code.HTTPStatus()
+	} else {
+		resp.Data = data
+	}
+	return resp, status
+}
+
+// negotiate choose the render by the Accept header, json by default.
+func negotiate(c *gin.Context, obj interface{}) render.Render {
+	switch c.NegotiateFormat(binding.MIMEJSON, binding.MIMEMSGPACK, binding.MIMEMSGPACK2) {
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		return render.MsgPack{Data: obj}
+	default:
+		return render.JSON{Data: obj}
+	}
+}
+
+// mutateContext return the context of the request,
+// carry request id and timezone like header.MutateContext.
+func mutateContext(c *gin.Context) context.Context {
+	var (
+		_requestID interface{} = header.RequestID
+		_timezone  interface{} = header.Timezone
+	)
+	ctx := c.Request.Context()
+	ctx = context.WithValue(ctx, _requestID, c.GetHeader(header.RequestID))
+	ctx = context.WithValue(ctx, _timezone, c.GetHeader(header.Timezone))
+	return ctx
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+// restPage page of the rest api, page starts from 1.
+type restPage struct {
+	Page int `form:"page,default=1"`
+	Size int `form:"size,default=10"`
+}
+
+// userList data of the users, the graphql ones carry the selected fields only.
+type userList struct {
+	Total int64            `json:"total"`
+	Users []*v1alpha1.User `json:"users"`
+}
+
+// departmentList data of the departments, the graphql ones carry the selected fields only.
+type departmentList struct {
+	Total       int64                  `json:"total"`
+	Departments []*v1alpha1.Department `json:"departments"`
+}
+
+// Users search the users by the query parameters bound into v1alpha1.SearchUser,
+// e.g. /rest/users?name=zhang&departmentID=x&orderBy=-createdAt&page=2.
+func (s *search) Users(c *gin.Context) {
+	query := &v1alpha1.SearchUser{}
+	page := &restPage{}
+	if err := bindRest(c, query, page); err != nil {
+		write(c, nil, service.Result{}, err)
+		return
+	}
+	query.TenantID = c.GetHeader("Tenant-Id")
+
+	resp, err := s.service().QueryUsers(mutateContext(c), query, page.Page, page.Size)
+	write(c, &userList{
+		Total: resp.Total,
+		Users: resp.Users,
+	}, resp.Result, err)
+}
+
+// Departments search the departments by the query parameters bound into v1alpha1.SearchDepartment,
+// e.g. /rest/departments?name=x&attr=1&attr=2.
+func (s *search) Departments(c *gin.Context) {
+	query := &v1alpha1.SearchDepartment{}
+	page := &restPage{}
+	if err := bindRest(c, query, page); err != nil {
+		write(c, nil, service.Result{}, err)
+		return
+	}
+	query.TenantID = c.GetHeader("Tenant-Id")
+
+	resp, err := s.service().QueryDepartments(mutateContext(c), query, page.Page, page.Size)
+	write(c, &departmentList{
+		Total:       resp.Total,
+		Departments: resp.Departments,
+	}, resp.Result, err)
+}
+
+// bindRest bind the query parameters into objs.
+func bindRest(c *gin.Context, objs ...interface{}) error {
+	for _, obj := range objs {
+		if err := c.ShouldBindQuery(obj); err != nil {
+			return errdefs.NewInvalidArgument("invalid query parameters: %s", err.Error())
+		}
+	}
+	return nil
+}
@@ -2,59 +2,116 @@ package api
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/quanxiang-cloud/cabin/logger"
-	"github.com/quanxiang-cloud/cabin/tailormade/db/elastic"
+	"github.com/go-logr/logr"
 	ginlogger "github.com/quanxiang-cloud/cabin/tailormade/gin"
 	"github.com/quanxiang-cloud/search/internal/config"
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/internal/models/resilience"
+	"github.com/quanxiang-cloud/search/internal/models/sqldb"
 	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/internal/tracing"
 	"github.com/quanxiang-cloud/search/pkg/probe"
 	"github.com/quanxiang-cloud/search/pkg/util"
+	"google.golang.org/grpc"
 )
 
 // Router router
 type Router struct {
 	router *gin.Engine
-	Probe  *probe.Probe
+	server *http.Server
+	// grpc nil if disabled
+	grpc  *grpc.Server
+	Probe *probe.Probe
+
+	log logr.Logger
+
+	// mu serialize reloads
+	mu sync.Mutex
+	// current *state, swapped by reload
+	current atomic.Value
+	// cancel stop the background workers
+	cancel context.CancelFunc
 }
 
 // NewRouter new
 func NewRouter(ctx context.Context, conf *config.Config) (*Router, error) {
 	e := gin.New()
-	e.Use(ginlogger.LoggerFunc(), ginlogger.RecoveryFunc())
+	e.Use(requestID(), requestLogger(util.LoggerFromContext(ctx)), tracing.Middleware(), metrics.Middleware(),
+		ginlogger.LoggerFunc(), ginlogger.RecoveryFunc())
 
-	log := util.LoggerFromContext(ctx).WithName("router")
-
-	// FIXME logger with logr
-	esClient, err := elastic.NewClient(&conf.Elasticsearch, logger.NewFromLogr(log))
+	state, err := newState(ctx, conf, nil)
 	if err != nil {
-		log.Error(err, "new elastic client")
 		return nil, err
 	}
 
-	v1 := e.Group("/api/v1/search")
+	r := &Router{
+		router: e,
+		server: &http.Server{
+			Handler: e,
+		},
+		log: util.LoggerFromContext(ctx).WithName("router"),
+	}
+	r.current.Store(state)
+
+	v1 := e.Group(basePath)
 	{
-		searchService, err := service.NewSearch(ctx,
-			service.WithES(ctx, esClient),
-		)
-		if err != nil {
-			log.Error(err, "new user service")
-			return nil, err
-		}
 		s := &search{
-			s: searchService,
+			current: func() *service.Search {
+				return r.state().search
+			},
 		}
-		v1.GET("/user", s.SearchUser)
-		v1.GET("/department", s.SearchDepartment)
-		v1.GET("/departments", s.DepartmentsByIDs)
-		v1.GET("/department/member", s.DepartmentMember)
-		v1.GET("/subordinate", s.Subordinate)
-		v1.GET("/leader", s.Leader)
-		v1.GET("/role/member", s.RoleMember)
-		v1.GET("/users", s.UserByIDs)
+		v1.GET("/user", r.timeout("/user"), s.SearchUser)
+		v1.GET("/department", r.timeout("/department"), s.SearchDepartment)
+		v1.GET("/departments", r.timeout("/departments"), s.DepartmentsByIDs)
+		v1.GET("/department/member", r.timeout("/department/member"), s.DepartmentMember)
+		v1.GET("/subordinate", r.timeout("/subordinate"), s.Subordinate)
+		v1.GET("/leader", r.timeout("/leader"), s.Leader)
+		v1.GET("/role/member", r.timeout("/role/member"), s.RoleMember)
+		v1.GET("/users", r.timeout("/users"), s.UserByIDs)
+
+		v1.GET("/rest/users", r.timeout("/rest/users"), s.Users)
+		v1.GET("/rest/departments", r.timeout("/rest/departments"), s.Departments)
+
+		v1.GET("/openapi.json", serveOpenAPI(newOpenAPI()))
+
+		v1.POST("/batch", r.timeout("/batch"), r.Batch)
+		v1.POST("/cache/invalidate", r.InvalidateCache)
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	// the storage down does not fail the readiness while the stale results are served,
+	// otherwise all replicas are drained by the load balancer.
+	staleEnabled := func() bool {
+		return r.state().conf.Stale.Enabled
+	}
+	checkers := elasticsearch.HealthCheckers(r.engine, func() []string {
+		return elasticsearch.IndexConfig(r.state().conf.Elasticsearch.Index).Indices()
+	}, conf.Health.MappingVersion)
+	checkers = append(checkers,
+		sqldb.HealthChecker(func() *sqldb.DB {
+			return r.state().sql
+		}),
+		resilience.HealthChecker(func() *resilience.Breaker {
+			return r.state().resilience.Breaker()
+		}),
+	)
+	for i, checker := range checkers {
+		checkers[i] = probe.Advisory(checker, staleEnabled)
 	}
 	probe := probe.New(util.LoggerFromContext(ctx))
+	probe.AddChecker(checkers...)
+	probe.Start(workerCtx, conf.Health.Interval, conf.Health.Timeout)
+	r.Probe = probe
 	{
 		e.GET("liveness", func(c *gin.Context) {
 			probe.LivenessProbe(c.Writer, c.Request)
@@ -65,14 +122,135 @@ func NewRouter(ctx context.Context, conf *config.Config) (*Router, error) {
 		})
 
 	}
-	return &Router{
-		router: e,
-		Probe:  probe,
-	}, nil
+	e.GET("metrics", metrics.Handler())
+	if conf.GRPC.Port != "" {
+		r.grpc = newGRPCServer(r)
+	}
+	return r, nil
+}
+
+func (r *Router) state() *state {
+	return r.current.Load().(*state)
+}
+
+func (r *Router) engine() engine.Engine {
+	return r.state().engine
+}
+
+// timeout set the deadline of the endpoint by the current config.
+func (r *Router) timeout(endpoint string) gin.HandlerFunc {
+	return timeout(func() time.Duration {
+		return r.state().conf.Timeout.Of(endpoint)
+	})
+}
+
+// Reload apply the validated config, the changed components and the search service
+// are rebuilt and swapped atomically, the in-flight requests finish with the old ones.
+// The current config is kept if any component fails to build.
+func (r *Router) Reload(ctx context.Context, conf *config.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.state()
+	reloaded := 0
+	for _, change := range config.Diff(old.conf, conf) {
+		if !isReloadable(change.Path) {
+			r.log.Info("config change takes effect after restart", "change", change.String())
+			continue
+		}
+		r.log.Info("config change", "change", change.String())
+		reloaded++
+	}
+	if reloaded == 0 {
+		return nil
+	}
+	// the state keeps the running values of the changes taking effect after restart,
+	// e.g. the port, so they are reported again by the next reload.
+	conf = config.Retain(old.conf, conf, isReloadable)
+
+	if err := util.SetLogLevel(conf.Log.Level); err != nil {
+		return err
+	}
+	state, err := newState(ctx, conf, old)
+	if err != nil {
+		return err
+	}
+	r.current.Store(state)
+	old.close(state)
+
+	r.log.Info("config reloaded", "changes", reloaded)
+	return nil
+}
+
+func persistedQueries(conf *config.PersistedQuery) (map[string]string, error) {
+	queries := make(map[string]string)
+	if conf.Dir != "" {
+		loaded, err := service.LoadPersistedQueries(conf.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for hash, query := range loaded {
+			queries[hash] = query
+		}
+	}
+	for hash, query := range conf.Queries {
+		queries[hash] = query
+	}
+	return queries, nil
+}
+
+// Handler return the http handler of the router, e.g. to serve it by httptest.
+func (r *Router) Handler() http.Handler {
+	return r.router
+}
+
+// Run start, http.ErrServerClosed is returned after Shutdown
+func (r *Router) Run(port string) error {
+	ln, err := net.Listen("tcp", port)
+	if err != nil {
+		return err
+	}
+	return r.server.Serve(ln)
+}
+
+// RunGRPC start the grpc server, nil is returned after Shutdown.
+func (r *Router) RunGRPC(port string) error {
+	if r.grpc == nil {
+		return errors.New("grpc server is disabled")
+	}
+	ln, err := net.Listen("tcp", port)
+	if err != nil {
+		return err
+	}
+	return r.grpc.Serve(ln)
 }
 
-// Run start
-func (r *Router) Run(port string) {
-	r.router.Run(port)
+// Shutdown stop accepting new connections and wait for the in-flight requests
+// of the http and grpc servers until ctx done.
+func (r *Router) Shutdown(ctx context.Context) error {
+	if r.grpc == nil {
+		return r.server.Shutdown(ctx)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		r.grpc.GracefulStop()
+		close(stopped)
+	}()
+	err := r.server.Shutdown(ctx)
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		r.grpc.Stop()
+	}
+	return err
+}
 
+// Close stop the background workers and the elasticsearch client,
+// must be called after Shutdown.
+func (r *Router) Close() {
+	r.cancel()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state().close(nil)
 }
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quanxiang-cloud/search/internal/config"
+)
+
+func TestReloadRetain(t *testing.T) {
+	r := newTestRouter(t)
+	old := r.state()
+
+	conf := *old.conf
+	conf.Port = ":81"
+	if err := r.Reload(context.Background(), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if r.state() != old {
+		t.Error("changes taking effect after restart: the state is rebuilt")
+	}
+
+	conf.Limits.MaxBatch = 5
+	if err := r.Reload(context.Background(), &conf); err != nil {
+		t.Fatal(err)
+	}
+	current := r.state().conf
+	if current.Limits.MaxBatch != 5 {
+		t.Errorf("reloadable change: maxBatch %d", current.Limits.MaxBatch)
+	}
+	if current.Port != old.conf.Port {
+		t.Errorf("change taking effect after restart: port %q is applied", current.Port)
+	}
+	if changes := config.Diff(current, &conf); len(changes) != 1 || changes[0].Path != "port" {
+		t.Errorf("pending changes: %v", changes)
+	}
+}
@@ -1,160 +1,184 @@
 package api
 
 import (
-	"net/http"
-	"reflect"
+	"encoding/json"
 
 	"github.com/gin-gonic/gin"
-	"github.com/quanxiang-cloud/cabin/tailormade/header"
 	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
 )
 
 type search struct {
-	s *service.Search
+	// current return the current search service, which is swapped by reload
+	current func() *service.Search
+}
+
+func (s *search) service() *service.Search {
+	return s.current()
 }
 
 func (s *search) SearchUser(c *gin.Context) {
-	query := c.Query("query")
+	query, hash, err := bindQuery(c)
+	if err != nil {
+		write(c, nil, service.Result{}, err)
+		return
+	}
 
 	req := &service.SearchUserReq{}
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.SearchUser(header.MutateContext(c), req)
+	req.Hash = hash
+	result, err := s.service().SearchUser(mutateContext(c), req)
+	write(c, result.Users, result.Result, err)
+}
 
+func (s *search) DepartmentMember(c *gin.Context) {
+	query, hash, err := bindQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		write(c, nil, service.Result{}, err)
 		return
 	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
-}
-
-func (s *search) DepartmentMember(c *gin.Context) {
-	query := c.Query("query")
 
 	req := &service.DepartmentMemberReq{}
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.DepartmentMember(header.MutateContext(c), req)
+	req.Hash = hash
+	result, err := s.service().DepartmentMember(mutateContext(c), req)
+	write(c, result.Users, result.Result, err)
+}
 
+func (s *search) Subordinate(c *gin.Context) {
+	query, hash, err := bindQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		write(c, nil, service.Result{}, err)
 		return
 	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
-}
-
-func (s *search) Subordinate(c *gin.Context) {
-	query := c.Query("query")
 
 	req := &service.SubordinateReq{}
 	req.UserID = c.GetHeader("User-Id")
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.Subordinate(header.MutateContext(c), req)
+	req.Hash = hash
+	result, err := s.service().Subordinate(mutateContext(c), req)
+	write(c, result.Users, result.Result, err)
+}
 
+func (s *search) Leader(c *gin.Context) {
+	query, hash, err := bindQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		write(c, nil, service.Result{}, err)
 		return
 	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
-}
-
-func (s *search) Leader(c *gin.Context) {
-	query := c.Query("query")
 
 	req := &service.LeaderReq{}
 	req.UserID = c.GetHeader("User-Id")
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.Leader(header.MutateContext(c), req)
+	req.Hash = hash
+	result, err := s.service().Leader(mutateContext(c), req)
+	write(c, result.Leaders, result.Result, err)
+}
 
+func (s *search) RoleMember(c *gin.Context) {
+	query, hash, err := bindQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		write(c, nil, service.Result{}, err)
 		return
 	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
-}
-
-func (s *search) RoleMember(c *gin.Context) {
-	query := c.Query("query")
 
 	req := &service.RoleMemberReq{}
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.RoleMember(header.MutateContext(c), req)
+	req.Hash = hash
+	result, err := s.service().RoleMember(mutateContext(c), req)
+	write(c, result.Users, result.Result, err)
+}
 
+func (s *search) UserByIDs(c *gin.Context) {
+	query, hash, err := bindQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		write(c, nil, service.Result{}, err)
 		return
 	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
-}
-
-func (s *search) UserByIDs(c *gin.Context) {
-	query := c.Query("query")
 
 	req := &service.UserByIDsReq{}
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.UserByIDs(header.MutateContext(c), req)
+	req.Hash = hash
+	result, err := s.service().UserByIDs(mutateContext(c), req)
+	write(c, result.Users, result.Result, err)
 
-	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
-		return
+}
+
+// bindQuery return the query and the persisted query hash,
+// the hash comes from the extensions of the Automatic Persisted Queries protocol.
+func bindQuery(c *gin.Context) (string, string, error) {
+	query := c.Query("query")
+
+	extensions := c.Query("extensions")
+	if extensions == "" {
+		return query, "", nil
 	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
 
+	hash, err := persistedHash([]byte(extensions))
+	if err != nil {
+		return "", "", err
+	}
+	return query, hash, nil
 }
 
-func transform(data interface{}, name string) map[string]interface{} {
-	result := map[string]interface{}{
-		"code": 0,
+// persistedHash return the persisted query hash of the extensions.
+func persistedHash(extensions []byte) (string, error) {
+	ext := struct {
+		PersistedQuery struct {
+			Version    int    `json:"version"`
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	}{}
+	if err := json.Unmarshal(extensions, &ext); err != nil {
+		return "", errdefs.NewInvalidArgument("invalid extensions")
 	}
-	if reflect.TypeOf(data).Kind() == reflect.Map {
-		value := reflect.ValueOf(data).MapIndex(reflect.ValueOf(name))
-		if value.CanInterface() {
-			result["data"] = value.Interface()
-		}
+	if ext.PersistedQuery.Sha256Hash != "" && ext.PersistedQuery.Version != 1 {
+		return "", errdefs.NewInvalidArgument("unsupported persisted query version")
 	}
-
-	return result
+	return ext.PersistedQuery.Sha256Hash, nil
 }
 
 func (s *search) SearchDepartment(c *gin.Context) {
-	query := c.Query("query")
+	query, hash, err := bindQuery(c)
+	if err != nil {
+		write(c, nil, service.Result{}, err)
+		return
+	}
 
 	req := &service.SearchDepartmentReq{}
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.SearchDepartment(header.MutateContext(c), req)
+	req.Hash = hash
+	result, err := s.service().SearchDepartment(mutateContext(c), req)
+	write(c, result.Departments, result.Result, err)
+}
 
+func (s *search) DepartmentsByIDs(c *gin.Context) {
+	query, hash, err := bindQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
+		write(c, nil, service.Result{}, err)
 		return
 	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
-}
-
-func (s *search) DepartmentsByIDs(c *gin.Context) {
-	query := c.Query("query")
 
 	req := &service.DepartmentsByIDsReq{}
 	req.TenantID = c.GetHeader("Tenant-Id")
 
 	req.Query = query
-	result, err := s.s.DepartmentByIDs(header.MutateContext(c), req)
-
-	if err != nil {
-		c.JSON(http.StatusBadRequest, err)
-		return
-	}
-	c.JSON(http.StatusOK, transform(result.Data, "query"))
+	req.Hash = hash
+	result, err := s.service().DepartmentByIDs(mutateContext(c), req)
+	write(c, result.Departments, result.Result, err)
 
 }
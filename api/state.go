@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/quanxiang-cloud/search/internal/config"
+	"github.com/quanxiang-cloud/search/internal/models/bleve"
+	"github.com/quanxiang-cloud/search/internal/models/cache"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/internal/models/memory"
+	"github.com/quanxiang-cloud/search/internal/models/resilience"
+	"github.com/quanxiang-cloud/search/internal/models/sqldb"
+	"github.com/quanxiang-cloud/search/internal/models/stale"
+	"github.com/quanxiang-cloud/search/internal/service"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// reloadable prefixes of the config paths applied by Reload,
+// the others take effect after restart.
+var reloadable = []string{
+	"storage.",
+	"elasticsearch.",
+	"cache.",
+	"resilience.",
+	"stale.",
+	"limits.",
+	"persistedQuery.",
+	"timeout.",
+	"log.level",
+	"log.slowQuery",
+}
+
+// state config and the components built from it.
+type state struct {
+	conf *config.Config
+	// engine nil if the storage is not elasticsearch
+	engine engine.Engine
+	// memory nil if the storage is not memory
+	memory *memory.Store
+	// sql nil if the storage is not sql
+	sql *sqldb.DB
+	// bleve nil if the storage is not bleve
+	bleve *bleve.Index
+	// cache nil if disabled
+	cache *cache.Cache
+	// resilience nil if disabled, the breaker is kept until its config changes
+	resilience *resilience.Resilience
+	// stale nil if disabled, the snapshot is kept until its config changes
+	stale  *stale.Stale
+	search *service.Search
+}
+
+// newState build the components of conf, the components of prev are reused
+// if their config is not changed, prev is nil for the first state.
+func newState(ctx context.Context, conf *config.Config, prev *state) (_ *state, err error) {
+	log := util.LoggerFromContext(ctx).WithName("router")
+
+	var changes []config.Change
+	if prev != nil {
+		changes = config.Diff(prev.conf, conf)
+	}
+	changed := func(prefix string, excludes ...string) bool {
+		if prev == nil {
+			return true
+		}
+		for _, change := range changes {
+			if strings.HasPrefix(change.Path, prefix) && !hasPrefix(change.Path, excludes...) {
+				return true
+			}
+		}
+		return false
+	}
+
+	s := &state{
+		conf: conf,
+	}
+	defer func() {
+		if err != nil {
+			s.close(prev)
+		}
+	}()
+
+	var storage service.Option
+	switch conf.Storage.Backend {
+	case config.StorageElasticsearch:
+		if prev == nil || prev.engine == nil || changed("elasticsearch.", "elasticsearch.index.") {
+			s.engine, err = elasticsearch.NewEngine(ctx, esConfig(&conf.Elasticsearch), log.WithName("elasticsearch"))
+			if err != nil {
+				log.Error(err, "new search engine")
+				return nil, err
+			}
+		} else {
+			s.engine = prev.engine
+		}
+		storage = service.WithES(ctx, s.engine,
+			elasticsearch.WithTimeout(conf.Timeout.Search),
+			elasticsearch.WithTerminateAfter(conf.Timeout.TerminateAfter),
+			elasticsearch.WithSlowThreshold(conf.Log.SlowQuery),
+			elasticsearch.WithIndex(elasticsearch.IndexConfig(conf.Elasticsearch.Index)),
+		)
+	case config.StorageMemory:
+		if prev == nil || prev.memory == nil || changed("storage.memory.") {
+			s.memory, err = memory.Load(memory.Config(conf.Storage.Memory))
+			if err != nil {
+				log.Error(err, "load memory storage")
+				return nil, err
+			}
+		} else {
+			s.memory = prev.memory
+		}
+		storage = service.WithMemory(s.memory)
+	case config.StorageSQL:
+		if prev == nil || prev.sql == nil || changed("storage.sql.") {
+			s.sql, err = sqldb.Open(ctx, sqldb.Config(conf.Storage.SQL))
+			if err != nil {
+				log.Error(err, "open sql storage")
+				return nil, err
+			}
+		} else {
+			s.sql = prev.sql
+		}
+		storage = service.WithSQL(s.sql)
+	case config.StorageBleve:
+		if prev == nil || prev.bleve == nil || changed("storage.bleve.") {
+			s.bleve, err = bleve.Open(bleve.Config(conf.Storage.Bleve))
+			if err != nil {
+				log.Error(err, "open bleve storage")
+				return nil, err
+			}
+		} else {
+			s.bleve = prev.bleve
+		}
+		storage = service.WithBleve(s.bleve)
+	}
+
+	if changed("cache.") {
+		s.cache, err = cache.New(ctx, cacheConfig(&conf.Cache))
+		if err != nil {
+			log.Error(err, "new cache")
+			return nil, err
+		}
+	} else {
+		s.cache = prev.cache
+	}
+
+	if changed("resilience.") {
+		s.resilience = resilience.New(ctx, resilienceConfig(&conf.Resilience))
+	} else {
+		s.resilience = prev.resilience
+	}
+
+	if changed("stale.") {
+		s.stale = stale.New(ctx, stale.Config(conf.Stale))
+	} else {
+		s.stale = prev.stale
+	}
+
+	queries, err := persistedQueries(&conf.PersistedQuery)
+	if err != nil {
+		log.Error(err, "load persisted queries")
+		return nil, err
+	}
+	s.search, err = service.NewSearch(ctx,
+		storage,
+		service.WithResilience(s.resilience),
+		service.WithStale(s.stale),
+		service.WithCache(s.cache),
+		service.WithLimits(service.Limits{
+			MaxDepth:      conf.Limits.MaxDepth,
+			MaxComplexity: conf.Limits.MaxComplexity,
+		}),
+		service.WithPersistedQuery(service.PersistedQuery{
+			Queries: queries,
+			Enforce: conf.PersistedQuery.Enforce,
+		}),
+		service.WithSlowThreshold(conf.Log.SlowQuery),
+	)
+	if err != nil {
+		log.Error(err, "new search service")
+		return nil, err
+	}
+	return s, nil
+}
+
+// esConfig return the elasticsearch config of conf.
+func esConfig(conf *config.Elasticsearch) *elasticsearch.Config {
+	return &elasticsearch.Config{
+		Engine:   conf.Engine,
+		Host:     conf.Host,
+		Log:      conf.Log,
+		Username: conf.Username,
+		Password: conf.Password,
+		Index:    elasticsearch.IndexConfig(conf.Index),
+	}
+}
+
+// cacheConfig return the cache config of conf.
+func cacheConfig(conf *config.Cache) cache.Config {
+	return cache.Config{
+		Backend: conf.Backend,
+		TTL:     conf.TTL,
+		Size:    conf.Size,
+		Redis:   cache.RedisConfig(conf.Redis),
+	}
+}
+
+// resilienceConfig return the resilience config of conf.
+func resilienceConfig(conf *config.Resilience) resilience.Config {
+	return resilience.Config{
+		Retries:    conf.Retries,
+		Backoff:    conf.Backoff,
+		MaxBackoff: conf.MaxBackoff,
+		Breaker:    resilience.BreakerConfig(conf.Breaker),
+	}
+}
+
+// close release the components not shared with next, next is nil to release all.
+// The in-flight requests are not interrupted, the search engine only stops
+// its sniffer and health checker or idle connections, the database waits for the started queries.
+func (s *state) close(next *state) {
+	if s.engine != nil && (next == nil || next.engine != s.engine) {
+		s.engine.Stop()
+	}
+	if s.sql != nil && (next == nil || next.sql != s.sql) {
+		_ = s.sql.Close()
+	}
+	if s.bleve != nil && (next == nil || next.bleve != s.bleve) {
+		_ = s.bleve.Close()
+	}
+	if s.cache != nil && (next == nil || next.cache != s.cache) {
+		_ = s.cache.Close()
+	}
+	if s.stale != nil && (next == nil || next.stale != s.stale) {
+		_ = s.stale.Close()
+	}
+}
+
+func isReloadable(path string) bool {
+	return hasPrefix(path, reloadable...)
+}
+
+func hasPrefix(path string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,33 +3,335 @@ package config
 import (
 	"context"
 	"io/ioutil"
+	"os"
+	"time"
 
-	"github.com/quanxiang-cloud/cabin/tailormade/db/elastic"
+	"github.com/quanxiang-cloud/search/internal/tracing"
 	"github.com/quanxiang-cloud/search/pkg/util"
 	"gopkg.in/yaml.v2"
 )
 
 // Config configuration item
 type Config struct {
-	Port          string         `yaml:"port"`
-	Elasticsearch elastic.Config `yaml:"elasticsearch"`
+	Port          string        `yaml:"port"`
+	GRPC          GRPC          `yaml:"grpc"`
+	Storage       Storage       `yaml:"storage"`
+	Elasticsearch Elasticsearch `yaml:"elasticsearch"`
+	Cache         Cache         `yaml:"cache"`
+	Resilience    Resilience    `yaml:"resilience"`
+	Stale         Stale         `yaml:"stale"`
+	Limits        Limits        `yaml:"limits"`
+
+	PersistedQuery PersistedQuery `yaml:"persistedQuery"`
+	Timeout        Timeout        `yaml:"timeout"`
+	Tracing        tracing.Config `yaml:"tracing"`
+	Health         Health         `yaml:"health"`
+	Shutdown       Shutdown       `yaml:"shutdown"`
+	Log            util.LogConfig `yaml:"log"`
+}
+
+// storage backends
+const (
+	StorageElasticsearch = "elasticsearch"
+	StorageMemory        = "memory"
+	StorageSQL           = "sql"
+	StorageBleve         = "bleve"
+)
+
+// Storage storage backend of the repos
+type Storage struct {
+	// Backend elasticsearch, memory, sql or bleve, default elasticsearch
+	Backend string `yaml:"backend"`
+	Memory  Memory `yaml:"memory"`
+	SQL     SQL    `yaml:"sql"`
+	Bleve   Bleve  `yaml:"bleve"`
+}
+
+// Memory memory backend
+type Memory struct {
+	// Fixtures path of the json file formed by {"users": [], "departments": []},
+	// the store is empty if not set.
+	Fixtures string `yaml:"fixtures"`
+}
+
+// sql drivers
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite3"
+)
+
+// SQL sql backend
+type SQL struct {
+	// Driver postgres, mysql or sqlite3
+	Driver string `yaml:"driver"`
+	// DSN data source name of the driver, e.g. file::memory:?cache=shared for sqlite3
+	DSN string `yaml:"dsn" secret:"true"`
+	// Migrate create the tables if not exist
+	Migrate bool `yaml:"migrate"`
+	// MaxOpenConns zero means unlimited
+	MaxOpenConns int `yaml:"maxOpenConns"`
+	// MaxIdleConns zero means the default of database/sql
+	MaxIdleConns int `yaml:"maxIdleConns"`
+	// ConnMaxLifetime zero means the connections are reused forever
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
+}
+
+// Bleve bleve backend
+type Bleve struct {
+	// Path directory of the indices, created if not exist,
+	// the indices are kept in memory if not set.
+	Path string `yaml:"path"`
+}
+
+// search engines
+const (
+	EngineES7        = "es7"
+	EngineES8        = "es8"
+	EngineOpenSearch = "opensearch"
+)
+
+// Elasticsearch elasticsearch backend
+type Elasticsearch struct {
+	// Engine es7, es8 or opensearch, default es7
+	Engine string   `yaml:"engine"`
+	Host   []string `yaml:"host"`
+	// Log log the requests
+	Log      bool   `yaml:"log"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+	// Index index names of the repos
+	Index Index `yaml:"index"`
+}
+
+// Index index names of the repos
+type Index struct {
+	// Prefix prefix of all index names, e.g. staging-
+	Prefix string `yaml:"prefix"`
+	// User name of the user index, default user
+	User string `yaml:"user"`
+	// Department name of the department index, default department
+	Department string `yaml:"department"`
+	// Dedicated tenants with dedicated indices named by the index name and the tenant,
+	// e.g. user-tenant1, the other tenants share the index.
+	Dedicated []string `yaml:"dedicated"`
+	// Routing route the requests of the shared index by the tenant,
+	// the documents must be indexed with the same routing.
+	Routing bool `yaml:"routing"`
+}
+
+// cache backends
+const (
+	// CacheNone the cache is disabled.
+	CacheNone = ""
+	// CacheMemory in-memory LRU of each instance.
+	CacheMemory = "memory"
+	// CacheRedis redis shared by the instances.
+	CacheRedis = "redis"
+)
+
+// Cache cache of the results
+type Cache struct {
+	// Backend memory, redis or empty for no cache
+	Backend string `yaml:"backend"`
+	// TTL time to live of the entries, default 1m
+	TTL time.Duration `yaml:"ttl"`
+	// Size max entries of the memory backend, default 10000
+	Size  int   `yaml:"size"`
+	Redis Redis `yaml:"redis"`
+	// InvalidateToken bearer token of the invalidation hook shared with the writers,
+	// the hook is disabled if empty. The memory backend is local to each replica,
+	// the hook only clears the replica receiving the request, the others serve
+	// the stale entries until the ttl, use the redis backend with more replicas.
+	InvalidateToken string `yaml:"invalidateToken" secret:"true"`
+}
+
+// Redis redis cache backend
+type Redis struct {
+	// Addrs a single address for the standalone server,
+	// multiple addresses for the cluster
+	Addrs    []string `yaml:"addrs"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password" secret:"true"`
+	DB       int      `yaml:"db"`
 }
 
-// New reuturn config from file path
-func New(ctx context.Context, path string) (*Config, error) {
+// Resilience retries and circuit breaker of the repo calls
+type Resilience struct {
+	// Retries max retries of the failed reads, zero means never
+	Retries int `yaml:"retries"`
+	// Backoff base delay before the first retry, doubled on each retry
+	// and fully jittered, default 50ms
+	Backoff time.Duration `yaml:"backoff"`
+	// MaxBackoff max delay before a retry, default 1s
+	MaxBackoff time.Duration `yaml:"maxBackoff"`
+	Breaker    Breaker       `yaml:"breaker"`
+}
+
+// Breaker circuit breaker
+type Breaker struct {
+	// Failures consecutive failures opening the breaker, zero means no breaker
+	Failures int `yaml:"failures"`
+	// Cooldown duration the breaker stays open before a trial call, default 10s
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// Stale stale-while-error
+type Stale struct {
+	// Enabled serve the last known good results while the storage is down,
+	// the failing storage checks are reported without failing the readiness
+	Enabled bool `yaml:"enabled"`
+	// TTL max age of the served results, default 24h
+	TTL time.Duration `yaml:"ttl"`
+	// Size max results kept by each instance, default 10000
+	Size int `yaml:"size"`
+}
+
+// Shutdown graceful shutdown
+type Shutdown struct {
+	// Drain time to wait after the readiness probe fails,
+	// letting the load balancer remove the instance, default 5s
+	Drain time.Duration `yaml:"drain"`
+	// Timeout max time to wait for the in-flight requests, default 10s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Health health checks of the readiness probe
+type Health struct {
+	// Interval interval of the checks, default 10s
+	Interval time.Duration `yaml:"interval"`
+	// Timeout timeout of each check, default 3s
+	Timeout time.Duration `yaml:"timeout"`
+	// MappingVersion expected _meta.version of the index mappings, skip if empty
+	MappingVersion string `yaml:"mappingVersion"`
+}
+
+// Timeout timeouts of the requests
+type Timeout struct {
+	// Default timeout of the endpoints
+	Default time.Duration `yaml:"default"`
+	// Endpoints timeout of the endpoint, keyed by the path under /api/v1/search
+	Endpoints map[string]time.Duration `yaml:"endpoints"`
+	// Search timeout of the search executed by elasticsearch shards
+	Search time.Duration `yaml:"search"`
+	// TerminateAfter max number of documents collected by each shard
+	TerminateAfter int `yaml:"terminateAfter"`
+}
+
+// Of return the timeout of the endpoint
+func (t *Timeout) Of(endpoint string) time.Duration {
+	if d, ok := t.Endpoints[endpoint]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// PersistedQuery persisted queries
+type PersistedQuery struct {
+	// Dir directory of *.graphql files
+	Dir string `yaml:"dir"`
+	// Queries hash to query text
+	Queries map[string]string `yaml:"queries"`
+	// Enforce only allow the registered hashes
+	Enforce bool `yaml:"enforce"`
+}
+
+// GRPC grpc server
+type GRPC struct {
+	// Port listen address of the grpc server, empty for disabled
+	Port string `yaml:"port"`
+}
+
+// Limits query limits
+type Limits struct {
+	MaxDepth int `yaml:"maxDepth"`
+	// MaxComplexity each selected field costs 1 and each list costs
+	// the documents it fetches, default 20000
+	MaxComplexity int `yaml:"maxComplexity"`
+	// MaxBatch max operations of a batch request, default 20
+	MaxBatch int `yaml:"maxBatch"`
+	// BatchWorkers operations of a batch request executed concurrently, default 4
+	BatchWorkers int `yaml:"batchWorkers"`
+}
+
+// Default return the config with the default values.
+func Default() *Config {
+	return &Config{
+		Port: ":80",
+		Storage: Storage{
+			Backend: StorageElasticsearch,
+		},
+		Elasticsearch: Elasticsearch{
+			Engine: EngineES7,
+		},
+		Resilience: Resilience{
+			Retries:    2,
+			Backoff:    50 * time.Millisecond,
+			MaxBackoff: time.Second,
+			Breaker: Breaker{
+				Failures: 5,
+				Cooldown: 10 * time.Second,
+			},
+		},
+		Limits: Limits{
+			MaxDepth:      10,
+			MaxComplexity: 20000,
+			MaxBatch:      20,
+			BatchWorkers:  4,
+		},
+		Health: Health{
+			Interval: 10 * time.Second,
+			Timeout:  3 * time.Second,
+		},
+		Shutdown: Shutdown{
+			Drain:   5 * time.Second,
+			Timeout: 10 * time.Second,
+		},
+		Log: util.LogConfig{
+			Level:    "info",
+			Encoding: "json",
+		},
+	}
+}
+
+// New return config layered by defaults, the file of path, the environment variables
+// and the overrides, the later layer wins. The file is skipped if path is empty.
+//
+// Every field can be set by the environment variable named by SEARCH_ and the yaml keys
+// of its path, e.g. SEARCH_TIMEOUT_DEFAULT=5s, the variable with suffix _FILE reads
+// the value from the file, e.g. SEARCH_ELASTICSEARCH_PASSWORD_FILE=/run/secrets/es.
+// Overrides are formed by the dotted yaml path and the value, e.g. timeout.default=5s.
+func New(ctx context.Context, path string, overrides ...string) (*Config, error) {
 	log := util.LoggerFromContext(ctx).WithName("config")
 
-	file, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
+	conf := Default()
+	if path != "" {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = yaml.Unmarshal(file, conf)
+		if err != nil {
+			log.Error(err, "yaml unmarshal")
+			return nil, err
+		}
 	}
 
-	var conf *Config
-	err = yaml.Unmarshal(file, &conf)
-	if err != nil {
-		log.Error(err, "yaml unmarshal")
+	if err := loadEnv(conf, envPrefix, os.LookupEnv); err != nil {
+		log.Error(err, "load env")
 		return nil, err
 	}
 
+	for _, override := range overrides {
+		if err := set(conf, override); err != nil {
+			log.Error(err, "override")
+			return nil, err
+		}
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
 	return conf, nil
 }
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const masked = "******"
+
+// Change change of the config field.
+type Change struct {
+	// Path dotted yaml path of the field
+	Path string
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff return the changed fields from old to new,
+// values of the fields tagged by secret:"true" are masked.
+func Diff(old, new *Config) []Change {
+	changes := make([]Change, 0)
+	diff(reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), "", false, &changes)
+	return changes
+}
+
+func diff(old, new reflect.Value, path string, secret bool, changes *[]Change) {
+	if old.Kind() == reflect.Struct {
+		for i := 0; i < old.NumField(); i++ {
+			field := old.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			diff(old.Field(i), new.Field(i), join(path, yamlKey(field)), field.Tag.Get("secret") == "true", changes)
+		}
+		return
+	}
+
+	if old.Kind() == reflect.Map {
+		keys := make(map[string]reflect.Value)
+		for _, key := range append(old.MapKeys(), new.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			o, n := old.MapIndex(keys[name]), new.MapIndex(keys[name])
+			if o.IsValid() && n.IsValid() && reflect.DeepEqual(o.Interface(), n.Interface()) {
+				continue
+			}
+			*changes = append(*changes, Change{
+				Path: join(path, name),
+				Old:  format(o, secret),
+				New:  format(n, secret),
+			})
+		}
+		return
+	}
+
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return
+	}
+	*changes = append(*changes, Change{
+		Path: path,
+		Old:  format(old, secret),
+		New:  format(new, secret),
+	})
+}
+
+// Retain return the copy of new keeping the values of old for the fields
+// whose dotted yaml path is not reloadable, the maps are kept as a whole.
+func Retain(old, new *Config, reloadable func(path string) bool) *Config {
+	conf := *new
+	retain(reflect.ValueOf(old).Elem(), reflect.ValueOf(&conf).Elem(), "", reloadable)
+	return &conf
+}
+
+func retain(old, new reflect.Value, path string, reloadable func(path string) bool) {
+	if old.Kind() == reflect.Struct {
+		for i := 0; i < old.NumField(); i++ {
+			field := old.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			retain(old.Field(i), new.Field(i), join(path, yamlKey(field)), reloadable)
+		}
+		return
+	}
+	if !reloadable(path) {
+		new.Set(old)
+	}
+}
+
+func format(v reflect.Value, secret bool) string {
+	switch {
+	case !v.IsValid():
+		return "<none>"
+	case secret:
+		return masked
+	case v.Kind() == reflect.Ptr && v.IsNil():
+		return "<nil>"
+	case v.Kind() == reflect.Ptr:
+		return fmt.Sprintf("%+v", v.Elem().Interface())
+	default:
+		return fmt.Sprintf("%v", v.Interface())
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(conf *Config)
+		changes []Change
+	}{{
+		name:    "no change",
+		change:  func(conf *Config) {},
+		changes: []Change{},
+	}, {
+		name: "nested field",
+		change: func(conf *Config) {
+			conf.Resilience.Breaker.Failures = 3
+		},
+		changes: []Change{{Path: "resilience.breaker.failures", Old: "5", New: "3"}},
+	}, {
+		name: "top level and duration",
+		change: func(conf *Config) {
+			conf.Port = ":81"
+			conf.Shutdown.Drain = time.Second
+		},
+		changes: []Change{
+			{Path: "port", Old: ":80", New: ":81"},
+			{Path: "shutdown.drain", Old: "5s", New: "1s"},
+		},
+	}, {
+		name: "slice",
+		change: func(conf *Config) {
+			conf.Elasticsearch.Host = []string{"http://es:9200"}
+		},
+		changes: []Change{{Path: "elasticsearch.host", Old: "[]", New: "[http://es:9200]"}},
+	}, {
+		name: "secret masked",
+		change: func(conf *Config) {
+			conf.Elasticsearch.Password = "s3cret"
+		},
+		changes: []Change{{Path: "elasticsearch.password", Old: masked, New: masked}},
+	}, {
+		name: "map keys",
+		change: func(conf *Config) {
+			conf.Timeout.Endpoints = map[string]time.Duration{"/user": time.Second}
+		},
+		changes: []Change{{Path: "timeout.endpoints./user", Old: "<none>", New: "1s"}},
+	}, {
+		name: "pointer",
+		change: func(conf *Config) {
+			conf.Log.Sampling = &util.LogSampling{Initial: 1, Thereafter: 2}
+		},
+		changes: []Change{{Path: "log.sampling", Old: "<nil>", New: "{Initial:1 Thereafter:2}"}},
+	}}
+	for _, tt := range tests {
+		new := Default()
+		tt.change(new)
+		if changes := Diff(Default(), new); !reflect.DeepEqual(changes, tt.changes) {
+			t.Errorf("%s: want %v, got %v", tt.name, tt.changes, changes)
+		}
+	}
+
+	old, new := Default(), Default()
+	old.Timeout.Endpoints = map[string]time.Duration{"/user": time.Second, "/leader": time.Second}
+	new.Timeout.Endpoints = map[string]time.Duration{"/user": 2 * time.Second}
+	want := []Change{
+		{Path: "timeout.endpoints./leader", Old: "1s", New: "<none>"},
+		{Path: "timeout.endpoints./user", Old: "1s", New: "2s"},
+	}
+	if changes := Diff(old, new); !reflect.DeepEqual(changes, want) {
+		t.Errorf("map changes: want %v, got %v", want, changes)
+	}
+}
+
+func TestRetain(t *testing.T) {
+	old, new := Default(), Default()
+	new.Port = ":81"
+	new.GRPC.Port = ":9090"
+	new.Log.Encoding = "console"
+	new.Log.Level = "debug"
+	new.Limits.MaxDepth = 3
+	new.Timeout.Endpoints = map[string]time.Duration{"/user": time.Second}
+
+	reloadable := func(path string) bool {
+		return strings.HasPrefix(path, "limits.") || strings.HasPrefix(path, "timeout.") || path == "log.level"
+	}
+	conf := Retain(old, new, reloadable)
+	want := Default()
+	want.Log.Level = "debug"
+	want.Limits.MaxDepth = 3
+	want.Timeout.Endpoints = map[string]time.Duration{"/user": time.Second}
+	if !reflect.DeepEqual(conf, want) {
+		t.Errorf("retain: changes %v", Diff(want, conf))
+	}
+	if new.Port != ":81" || new.Log.Encoding != "console" {
+		t.Error("retain: new is changed")
+	}
+}
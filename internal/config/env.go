@@ -0,0 +1,242 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	envPrefix = "SEARCH"
+	// fileSuffix suffix of the environment variables holding the path of the value
+	fileSuffix = "_FILE"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Overrides overrides set by command line, implements flag.Value.
+type Overrides []string
+
+func (o *Overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+// Set append the override formed by path=value.
+func (o *Overrides) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("override %q must be formed by path=value", value)
+	}
+	*o = append(*o, value)
+	return nil
+}
+
+// loadEnv set the fields of conf from the environment variables looked up by lookup.
+func loadEnv(conf interface{}, prefix string, lookup func(string) (string, bool)) error {
+	return walkEnv(reflect.ValueOf(conf).Elem(), prefix, lookup)
+}
+
+func walkEnv(v reflect.Value, name string, lookup func(string) (string, bool)) error {
+	if v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct {
+		// only allocate the struct if any of its fields is set.
+		elem := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			elem.Elem().Set(v.Elem())
+		}
+		if err := walkEnv(elem.Elem(), name, lookup); err != nil {
+			return err
+		}
+		if !v.IsNil() || !reflect.DeepEqual(elem.Elem().Interface(), reflect.Zero(elem.Type().Elem()).Interface()) {
+			v.Set(elem)
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			key := strings.ToUpper(yamlKey(field))
+			if err := walkEnv(v.Field(i), name+"_"+key, lookup); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, ok := lookup(name)
+	if !ok {
+		path, ok := lookup(name + fileSuffix)
+		if !ok {
+			return nil
+		}
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name+fileSuffix, err)
+		}
+		value = strings.TrimSpace(string(file))
+	}
+	if err := setValue(v, value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// set set the field of conf by the override formed by path=value,
+// path is formed by the yaml keys joined by dot, the rest of the path
+// after a map field is the map key, e.g. timeout.endpoints./users=5s.
+func set(conf interface{}, override string) error {
+	i := strings.Index(override, "=")
+	if i < 0 {
+		return fmt.Errorf("override %q must be formed by path=value", override)
+	}
+	path, value := override[:i], override[i+1:]
+
+	v := reflect.ValueOf(conf).Elem()
+	keys := strings.Split(path, ".")
+	for i := 0; i < len(keys); i++ {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByKey(v, keys[i])
+			if !ok {
+				return fmt.Errorf("override %q: unknown key %q", override, keys[i])
+			}
+			v = field
+		case reflect.Map:
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, value); err != nil {
+				return fmt.Errorf("override %q: %w", override, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(strings.Join(keys[i:], ".")), elem)
+			return nil
+		default:
+			return fmt.Errorf("override %q: %q is not an object", override, strings.Join(keys[:i], "."))
+		}
+	}
+
+	if err := setValue(v, value); err != nil {
+		return fmt.Errorf("override %q: %w", override, err)
+	}
+	return nil
+}
+
+func fieldByKey(v reflect.Value, key string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath == "" && strings.EqualFold(yamlKey(field), key) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// yamlKey return the key of the field like yaml.v2.
+func yamlKey(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if tag != "" && tag != "-" {
+		return tag
+	}
+	return strings.ToLower(field.Name)
+}
+
+// setValue parse value into v, slices are separated by comma,
+// maps are formed by key=value separated by comma.
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := splitList(value)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range splitList(value) {
+			kv := strings.SplitN(item, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("%q must be formed by key=value", item)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setValue(key, kv[0]); err != nil {
+				return err
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, kv[1]); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type envInner struct {
+	Name    string        `yaml:"name"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type envTest struct {
+	Port     string            `yaml:"port"`
+	Enabled  bool              `yaml:"enabled"`
+	Size     int               `yaml:"size"`
+	Ratio    float64           `yaml:"ratio"`
+	Hosts    []string          `yaml:"hosts"`
+	Ports    []int             `yaml:"ports"`
+	Labels   map[string]string `yaml:"labels"`
+	Inner    envInner          `yaml:"inner"`
+	Optional *envInner         `yaml:"optional"`
+	NoTag    string
+	private  string
+}
+
+func lookupOf(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := ioutil.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		expect envTest
+		err    bool
+	}{{
+		name:   "no env",
+		env:    map[string]string{},
+		expect: envTest{Port: ":80"},
+	}, {
+		name: "scalars",
+		env: map[string]string{
+			"T_PORT":    ":81",
+			"T_ENABLED": "true",
+			"T_SIZE":    "10",
+			"T_RATIO":   "0.5",
+			"T_NOTAG":   "x",
+			"T_PRIVATE": "x",
+		},
+		expect: envTest{Port: ":81", Enabled: true, Size: 10, Ratio: 0.5, NoTag: "x"},
+	}, {
+		name: "slices and map",
+		env: map[string]string{
+			"T_HOSTS":  "http://a:9200, http://b:9200,",
+			"T_PORTS":  "1,2",
+			"T_LABELS": "a=1,b=2",
+		},
+		expect: envTest{
+			Port:   ":80",
+			Hosts:  []string{"http://a:9200", "http://b:9200"},
+			Ports:  []int{1, 2},
+			Labels: map[string]string{"a": "1", "b": "2"},
+		},
+	}, {
+		name: "nested struct and duration",
+		env: map[string]string{
+			"T_INNER_NAME":    "inner",
+			"T_INNER_TIMEOUT": "1m30s",
+		},
+		expect: envTest{Port: ":80", Inner: envInner{Name: "inner", Timeout: 90 * time.Second}},
+	}, {
+		name: "pointer struct allocated if set",
+		env: map[string]string{
+			"T_OPTIONAL_TIMEOUT": "1s",
+		},
+		expect: envTest{Port: ":80", Optional: &envInner{Timeout: time.Second}},
+	}, {
+		name: "file suffix",
+		env: map[string]string{
+			"T_INNER_NAME_FILE": secret,
+		},
+		expect: envTest{Port: ":80", Inner: envInner{Name: "s3cret"}},
+	}, {
+		name: "value wins the file",
+		env: map[string]string{
+			"T_INNER_NAME":      "inner",
+			"T_INNER_NAME_FILE": secret,
+		},
+		expect: envTest{Port: ":80", Inner: envInner{Name: "inner"}},
+	}, {
+		name: "missing file",
+		env:  map[string]string{"T_PORT_FILE": filepath.Join(t.TempDir(), "missing")},
+		err:  true,
+	}, {
+		name: "invalid duration",
+		env:  map[string]string{"T_INNER_TIMEOUT": "1"},
+		err:  true,
+	}, {
+		name: "invalid int",
+		env:  map[string]string{"T_PORTS": "1,x"},
+		err:  true,
+	}, {
+		name: "invalid map",
+		env:  map[string]string{"T_LABELS": "a"},
+		err:  true,
+	}}
+	for _, tt := range tests {
+		conf := envTest{Port: ":80"}
+		err := loadEnv(&conf, "T", lookupOf(tt.env))
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expect error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(conf, tt.expect) {
+			t.Errorf("%s: got %+v, expect %+v", tt.name, conf, tt.expect)
+		}
+	}
+}
+
+func TestLoadEnvConfig(t *testing.T) {
+	conf := Default()
+	err := loadEnv(conf, envPrefix, lookupOf(map[string]string{
+		"SEARCH_ELASTICSEARCH_HOST":             "http://a:9200,http://b:9200",
+		"SEARCH_RESILIENCE_BREAKER_COOLDOWN":    "1m",
+		"SEARCH_TIMEOUT_ENDPOINTS":              "/users=5s",
+		"SEARCH_LOG_SAMPLING_INITIAL":           "100",
+		"SEARCH_STORAGE_SQL_CONNMAXLIFETIME":    "1h",
+		"SEARCH_ELASTICSEARCH_INDEX_DEDICATED":  "t1,t2",
+		"SEARCH_CACHE_REDIS_ADDRS":              "redis:6379",
+		"SEARCH_PERSISTEDQUERY_ENFORCE":         "true",
+		"SEARCH_ELASTICSEARCH_INDEX_ROUTING":    "true",
+		"SEARCH_TRACING_SAMPLERATIO":            "0.1",
+		"SEARCH_HEALTH_MAPPINGVERSION":          "2",
+		"SEARCH_LIMITS_MAXCOMPLEXITY":           "100",
+		"SEARCH_STALE_ENABLED":                  "true",
+		"SEARCH_ELASTICSEARCH_INDEX_DEPARTMENT": "dep",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case !reflect.DeepEqual(conf.Elasticsearch.Host, []string{"http://a:9200", "http://b:9200"}):
+		t.Errorf("elasticsearch.host: %v", conf.Elasticsearch.Host)
+	case conf.Resilience.Breaker.Cooldown != time.Minute || conf.Resilience.Breaker.Failures != 5:
+		t.Errorf("resilience.breaker: %+v", conf.Resilience.Breaker)
+	case conf.Timeout.Endpoints["/users"] != 5*time.Second:
+		t.Errorf("timeout.endpoints: %v", conf.Timeout.Endpoints)
+	case conf.Log.Sampling == nil || conf.Log.Sampling.Initial != 100:
+		t.Errorf("log.sampling: %+v", conf.Log.Sampling)
+	case conf.Storage.SQL.ConnMaxLifetime != time.Hour:
+		t.Errorf("storage.sql.connMaxLifetime: %s", conf.Storage.SQL.ConnMaxLifetime)
+	case !reflect.DeepEqual(conf.Elasticsearch.Index, Index{Department: "dep", Dedicated: []string{"t1", "t2"}, Routing: true}):
+		t.Errorf("elasticsearch.index: %+v", conf.Elasticsearch.Index)
+	case !conf.PersistedQuery.Enforce || !conf.Stale.Enabled || conf.Limits.MaxComplexity != 100:
+		t.Errorf("config: %+v", conf)
+	case conf.Tracing.SampleRatio != 0.1 || conf.Health.MappingVersion != "2":
+		t.Errorf("config: %+v", conf)
+	}
+	if conf.Log.Sampling != nil && conf.Log.Sampling.Thereafter != 0 {
+		t.Errorf("log.sampling: unset fields must keep zero, got %+v", conf.Log.Sampling)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		override string
+		check    func(conf *Config) bool
+		err      string
+	}{{
+		override: "port=:81",
+		check:    func(conf *Config) bool { return conf.Port == ":81" },
+	}, {
+		override: "resilience.breaker.cooldown=1m",
+		check:    func(conf *Config) bool { return conf.Resilience.Breaker.Cooldown == time.Minute },
+	}, {
+		// keys are case insensitive like the environment variables
+		override: "limits.MAXDEPTH=3",
+		check:    func(conf *Config) bool { return conf.Limits.MaxDepth == 3 },
+	}, {
+		override: "elasticsearch.host=http://a:9200,http://b:9200",
+		check: func(conf *Config) bool {
+			return reflect.DeepEqual(conf.Elasticsearch.Host, []string{"http://a:9200", "http://b:9200"})
+		},
+	}, {
+		// the rest of the path after a map is the key
+		override: "timeout.endpoints./department/member=3s",
+		check:    func(conf *Config) bool { return conf.Timeout.Endpoints["/department/member"] == 3*time.Second },
+	}, {
+		override: "timeout.endpoints=/users=1s,/user=2s",
+		check: func(conf *Config) bool {
+			return reflect.DeepEqual(conf.Timeout.Endpoints, map[string]time.Duration{"/users": time.Second, "/user": 2 * time.Second})
+		},
+	}, {
+		override: "log.sampling.thereafter=10",
+		check:    func(conf *Config) bool { return conf.Log.Sampling != nil && conf.Log.Sampling.Thereafter == 10 },
+	}, {
+		override: "elasticsearch.password=a=b",
+		check:    func(conf *Config) bool { return conf.Elasticsearch.Password == "a=b" },
+	}, {
+		override: "port",
+		err:      "must be formed by path=value",
+	}, {
+		override: "unknown=1",
+		err:      `unknown key "unknown"`,
+	}, {
+		override: "port.x=1",
+		err:      `"port" is not an object`,
+	}, {
+		override: "limits.maxDepth=x",
+		err:      "invalid syntax",
+	}, {
+		override: "timeout.default=1",
+		err:      "missing unit",
+	}}
+	for _, tt := range tests {
+		conf := Default()
+		err := set(conf, tt.override)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, expect %q", tt.override, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.override, err)
+			continue
+		}
+		if !tt.check(conf) {
+			t.Errorf("%s: not set", tt.override)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	var o Overrides
+	for _, value := range []string{"port=:81", "timeout.default=1s"} {
+		if err := o.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := o.Set("port"); err == nil {
+		t.Error("override without value: expect error")
+	}
+	if o.String() != "port=:81,timeout.default=1s" {
+		t.Errorf("overrides: %s", o.String())
+	}
+}
+
+func TestSecretsMasked(t *testing.T) {
+	secrets := map[string]func(conf *Config){
+		"elasticsearch.password": func(conf *Config) { conf.Elasticsearch.Password = "s3cret" },
+		"storage.sql.dsn":        func(conf *Config) { conf.Storage.SQL.DSN = "postgres://u:s3cret@db/search" },
+		"cache.redis.password":   func(conf *Config) { conf.Cache.Redis.Password = "s3cret" },
+		"cache.invalidateToken":  func(conf *Config) { conf.Cache.InvalidateToken = "s3cret" },
+	}
+	for path, change := range secrets {
+		conf := Default()
+		change(conf)
+		changes := Diff(Default(), conf)
+		if len(changes) != 1 || changes[0].Path != path {
+			t.Errorf("%s: changes %v", path, changes)
+			continue
+		}
+		if changes[0].Old != masked || changes[0].New != masked || strings.Contains(changes[0].String(), "s3cret") {
+			t.Errorf("%s: not masked, %s", path, changes[0])
+		}
+	}
+
+	// every field tagged by secret:"true" is listed above
+	var walk func(typ reflect.Type, path string)
+	walk = func(typ reflect.Type, path string) {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || typ == durationType {
+			return
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			fieldPath := join(path, yamlKey(field))
+			if field.Tag.Get("secret") == "true" {
+				if _, ok := secrets[fieldPath]; !ok {
+					t.Errorf("%s: secret field is not tested", fieldPath)
+				}
+			}
+			walk(field.Type, fieldPath)
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+}
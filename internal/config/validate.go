@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"go.uber.org/zap/zapcore"
+)
+
+// ValidationError invalid fields of the config.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e, "; ")
+}
+
+func (e *ValidationError) add(path, format string, args ...interface{}) {
+	*e = append(*e, path+": "+fmt.Sprintf(format, args...))
+}
+
+// Validate return ValidationError listing all invalid fields.
+func (c *Config) Validate() error {
+	errs := ValidationError{}
+
+	if c.Port == "" {
+		errs.add("port", "is must, e.g. :80")
+	}
+	if c.GRPC.Port != "" && c.GRPC.Port == c.Port {
+		errs.add("grpc.port", "must differ from port %q", c.Port)
+	}
+	switch c.Storage.Backend {
+	case StorageElasticsearch:
+		if len(c.Elasticsearch.Host) == 0 {
+			errs.add("elasticsearch.host", "at least one host is must, e.g. http://elasticsearch:9200")
+		}
+	case StorageMemory:
+	case StorageSQL:
+		switch c.Storage.SQL.Driver {
+		case DriverPostgres, DriverMySQL, DriverSQLite:
+		default:
+			errs.add("storage.sql.driver", "must be %q, %q or %q, got %q",
+				DriverPostgres, DriverMySQL, DriverSQLite, c.Storage.SQL.Driver)
+		}
+		if c.Storage.SQL.DSN == "" {
+			errs.add("storage.sql.dsn", "is must for the sql backend")
+		}
+	case StorageBleve:
+	default:
+		errs.add("storage.backend", "must be %q, %q, %q or %q, got %q",
+			StorageElasticsearch, StorageMemory, StorageSQL, StorageBleve, c.Storage.Backend)
+	}
+	if c.Storage.SQL.MaxOpenConns < 0 || c.Storage.SQL.MaxIdleConns < 0 {
+		errs.add("storage.sql", "the connections must not be negative")
+	}
+	switch c.Elasticsearch.Engine {
+	case "", EngineES7, EngineES8, EngineOpenSearch:
+	default:
+		errs.add("elasticsearch.engine", "must be %q, %q or %q, got %q",
+			EngineES7, EngineES8, EngineOpenSearch, c.Elasticsearch.Engine)
+	}
+	if c.Elasticsearch.Password != "" && c.Elasticsearch.Username == "" {
+		errs.add("elasticsearch.username", "is must if the password is set")
+	}
+
+	index := c.Elasticsearch.Index
+	names := [][2]string{
+		{"elasticsearch.index.prefix", index.Prefix},
+		{"elasticsearch.index.user", index.User},
+		{"elasticsearch.index.department", index.Department},
+	}
+	for _, tenantID := range index.Dedicated {
+		names = append(names, [2]string{"elasticsearch.index.dedicated", tenantID})
+	}
+	for _, name := range names {
+		if name[1] != strings.ToLower(name[1]) || strings.ContainsAny(name[1], `\/*?"<>| ,#:`) {
+			errs.add(name[0], "invalid index name %q, must be lowercase without \\/*?\"<>| ,#:", name[1])
+		}
+	}
+
+	switch c.Cache.Backend {
+	case CacheNone, CacheMemory:
+	case CacheRedis:
+		if len(c.Cache.Redis.Addrs) == 0 {
+			errs.add("cache.redis.addrs", "at least one address is must for the redis backend")
+		}
+	default:
+		errs.add("cache.backend", "must be empty, %q or %q, got %q", CacheMemory, CacheRedis, c.Cache.Backend)
+	}
+	if c.Cache.TTL < 0 {
+		errs.add("cache.ttl", "must not be negative, got %s", c.Cache.TTL)
+	}
+	if c.Cache.Size < 0 {
+		errs.add("cache.size", "must not be negative, got %d", c.Cache.Size)
+	}
+
+	if c.Resilience.Retries < 0 {
+		errs.add("resilience.retries", "must not be negative, got %d", c.Resilience.Retries)
+	}
+	if c.Resilience.Backoff < 0 {
+		errs.add("resilience.backoff", "must not be negative, got %s", c.Resilience.Backoff)
+	}
+	if c.Resilience.MaxBackoff < 0 {
+		errs.add("resilience.maxBackoff", "must not be negative, got %s", c.Resilience.MaxBackoff)
+	}
+	if c.Resilience.Breaker.Failures < 0 {
+		errs.add("resilience.breaker.failures", "must not be negative, got %d", c.Resilience.Breaker.Failures)
+	}
+	if c.Resilience.Breaker.Cooldown < 0 {
+		errs.add("resilience.breaker.cooldown", "must not be negative, got %s", c.Resilience.Breaker.Cooldown)
+	}
+
+	if c.Stale.TTL < 0 {
+		errs.add("stale.ttl", "must not be negative, got %s", c.Stale.TTL)
+	}
+	if c.Stale.Size < 0 {
+		errs.add("stale.size", "must not be negative, got %d", c.Stale.Size)
+	}
+
+	if c.Limits.MaxDepth < 0 {
+		errs.add("limits.maxDepth", "must not be negative, got %d", c.Limits.MaxDepth)
+	}
+	if c.Limits.MaxComplexity < 0 {
+		errs.add("limits.maxComplexity", "must not be negative, got %d", c.Limits.MaxComplexity)
+	}
+	if c.Limits.MaxBatch < 1 {
+		errs.add("limits.maxBatch", "must be positive, got %d", c.Limits.MaxBatch)
+	}
+	if c.Limits.BatchWorkers < 1 {
+		errs.add("limits.batchWorkers", "must be positive, got %d", c.Limits.BatchWorkers)
+	}
+
+	if c.Timeout.Default < 0 {
+		errs.add("timeout.default", "must not be negative, got %s", c.Timeout.Default)
+	}
+	for endpoint, d := range c.Timeout.Endpoints {
+		if !strings.HasPrefix(endpoint, "/") {
+			errs.add("timeout.endpoints", "endpoint %q must start with /", endpoint)
+		}
+		if d < 0 {
+			errs.add("timeout.endpoints."+endpoint, "must not be negative, got %s", d)
+		}
+	}
+	if c.Timeout.Search < 0 {
+		errs.add("timeout.search", "must not be negative, got %s", c.Timeout.Search)
+	}
+	if c.Timeout.TerminateAfter < 0 {
+		errs.add("timeout.terminateAfter", "must not be negative, got %d", c.Timeout.TerminateAfter)
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP:
+	default:
+		errs.add("tracing.exporter", "must be empty or %q, got %q", tracing.ExporterOTLP, c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs.add("tracing.sampleRatio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if c.Health.Interval <= 0 {
+		errs.add("health.interval", "must be positive, got %s", c.Health.Interval)
+	}
+	if c.Health.Timeout <= 0 {
+		errs.add("health.timeout", "must be positive, got %s", c.Health.Timeout)
+	}
+
+	if c.Shutdown.Drain < 0 {
+		errs.add("shutdown.drain", "must not be negative, got %s", c.Shutdown.Drain)
+	}
+	if c.Shutdown.Timeout <= 0 {
+		errs.add("shutdown.timeout", "must be positive, got %s", c.Shutdown.Timeout)
+	}
+
+	if c.Log.Level != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+			errs.add("log.level", "must be one of debug, info, warn or error, got %q", c.Log.Level)
+		}
+	}
+	switch c.Log.Encoding {
+	case "", "json", "console":
+	default:
+		errs.add("log.encoding", "must be json or console, got %q", c.Log.Encoding)
+	}
+	if c.Log.Sampling != nil && (c.Log.Sampling.Initial < 0 || c.Log.Sampling.Thereafter < 0) {
+		errs.add("log.sampling", "must not be negative")
+	}
+	if c.Log.SlowQuery < 0 {
+		errs.add("log.slowQuery", "must not be negative, got %s", c.Log.SlowQuery)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// debounce editors and config maps write the file by several events.
+const debounce = 500 * time.Millisecond
+
+// Watch reload the config from path, the environment variables and the overrides
+// when the file changes, until ctx done. onChange is called with the reloaded config,
+// the invalid config is logged and rejected.
+func Watch(ctx context.Context, path string, overrides []string, onChange func(*Config) error) error {
+	log := util.LoggerFromContext(ctx).WithName("config")
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// watch the directory, the file is replaced rather than written
+	// by the symlink swap of kubernetes config maps.
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !affect(path, event) {
+					continue
+				}
+				timer.Reset(debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error(err, "watch")
+			case <-timer.C:
+				conf, err := New(ctx, path, overrides...)
+				if err != nil {
+					log.Error(err, "reject config")
+					continue
+				}
+				if err := onChange(conf); err != nil {
+					log.Error(err, "reject config")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func affect(path string, event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	return name == filepath.Clean(path) ||
+		// the data directory of the config map is swapped
+		filepath.Base(name) == "..data"
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchDebounce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(maxDepth string) {
+		if err := ioutil.WriteFile(path, []byte("storage:\n  backend: memory\nlimits:\n  maxDepth: "+maxDepth+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls, maxDepth int32
+	err := Watch(ctx, path, nil, func(conf *Config) error {
+		atomic.AddInt32(&calls, 1)
+		atomic.StoreInt32(&maxDepth, int32(conf.Limits.MaxDepth))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the writes within the debounce reload once with the last content.
+	for _, depth := range []string{"2", "3", "4"} {
+		write(depth)
+		time.Sleep(debounce / 5)
+	}
+	time.Sleep(debounce * 2)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("writes within the debounce: %d reloads", n)
+	}
+	if d := atomic.LoadInt32(&maxDepth); d != 4 {
+		t.Errorf("writes within the debounce: maxDepth %d", d)
+	}
+
+	// the invalid config is rejected.
+	write("-1")
+	time.Sleep(debounce * 2)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("invalid config: %d reloads", n)
+	}
+}
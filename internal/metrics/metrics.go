@@ -0,0 +1,218 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "search"
+
+var (
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the http requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpResponseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "response_size_bytes",
+		Help:      "Size of the http responses.",
+		Buckets:   prometheus.ExponentialBuckets(128, 4, 8),
+	}, []string{"method", "route"})
+
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the grpc requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	graphqlDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "graphql",
+		Name:      "execution_duration_seconds",
+		Help:      "Execution time of the graphql queries.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"schema", "operation"})
+
+	graphqlErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "graphql",
+		Name:      "errors_total",
+		Help:      "Errors of the graphql queries.",
+	}, []string{"schema", "operation", "code"})
+
+	esDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "elasticsearch",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the elasticsearch requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"repo", "method"})
+
+	esErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "elasticsearch",
+		Name:      "errors_total",
+		Help:      "Errors of the elasticsearch requests.",
+	}, []string{"repo", "method", "code"})
+
+	esResultSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "elasticsearch",
+		Name:      "result_size",
+		Help:      "Number of documents returned by the elasticsearch requests.",
+		Buckets:   []float64{0, 1, 5, 10, 20, 50, 100, 200, 500, 1000},
+	}, []string{"repo", "method"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Lookups of the result cache.",
+	}, []string{"kind", "method", "result"})
+
+	resilienceRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "resilience",
+		Name:      "retries_total",
+		Help:      "Retries of the failed repo calls.",
+	}, []string{"kind", "method"})
+
+	breakerRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "resilience",
+		Name:      "breaker_rejections_total",
+		Help:      "Repo calls failed fast by the open circuit breaker.",
+	}, []string{"kind", "method"})
+
+	staleResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "stale",
+		Name:      "results_total",
+		Help:      "Last known good results served while the storage is down.",
+	}, []string{"kind", "method"})
+
+	breakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "resilience",
+		Name:      "breaker_state",
+		Help:      "State of the circuit breaker, 0 closed, 1 half-open, 2 open.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		httpDuration,
+		httpResponseSize,
+		grpcDuration,
+		graphqlDuration,
+		graphqlErrors,
+		esDuration,
+		esErrors,
+		esResultSize,
+		cacheRequests,
+		resilienceRetries,
+		breakerRejections,
+		breakerState,
+		staleResults,
+	)
+}
+
+// Handler return the handler of /metrics.
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// Middleware observe the latency and the response size of the http requests.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			// unmatched routes share one label, avoiding high cardinality.
+			route = "unknown"
+		}
+		httpDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+		if size := c.Writer.Size(); size >= 0 {
+			httpResponseSize.WithLabelValues(c.Request.Method, route).Observe(float64(size))
+		}
+	}
+}
+
+// UnaryServerInterceptor observe the latency of the grpc requests like Middleware.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		grpcDuration.WithLabelValues(info.FullMethod, status.Code(err).String()).
+			Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// ObserveGraphQL observe the execution of the graphql query, operation must be
+// one of a bounded set, never the operation name sent by the client.
+func ObserveGraphQL(schema, operation string, start time.Time, errs ...error) {
+	graphqlDuration.WithLabelValues(schema, operation).Observe(time.Since(start).Seconds())
+	for _, err := range errs {
+		graphqlErrors.WithLabelValues(schema, operation, errdefs.From(err).Code.String()).Inc()
+	}
+}
+
+// ObserveES observe the elasticsearch request of the repo method,
+// size is the number of documents returned.
+func ObserveES(repo, method string, start time.Time, size int, err error) {
+	esDuration.WithLabelValues(repo, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		esErrors.WithLabelValues(repo, method, errdefs.From(err).Code.String()).Inc()
+		return
+	}
+	esResultSize.WithLabelValues(repo, method).Observe(float64(size))
+}
+
+// ObserveCache observe the cache lookup of the repo method.
+func ObserveCache(kind, method string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(kind, method, result).Inc()
+}
+
+// ObserveRetry observe the retry of the repo method.
+func ObserveRetry(kind, method string) {
+	resilienceRetries.WithLabelValues(kind, method).Inc()
+}
+
+// ObserveBreakerRejection observe the call of the repo method rejected by the breaker.
+func ObserveBreakerRejection(kind, method string) {
+	breakerRejections.WithLabelValues(kind, method).Inc()
+}
+
+// SetBreakerState set the state of the circuit breaker.
+func SetBreakerState(state int) {
+	breakerState.Set(float64(state))
+}
+
+// ObserveStale observe the stale result served by the repo method.
+func ObserveStale(kind, method string) {
+	staleResults.WithLabelValues(kind, method).Inc()
+}
@@ -0,0 +1,92 @@
+package bleve
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	blevesearch "github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+var departmentText = map[string]bool{"name": true}
+
+type department struct {
+	index blevesearch.Index
+}
+
+// NewDepartment return the department repo of the index.
+func NewDepartment(index *Index) models.DepartmentRepo {
+	return &department{
+		index: index.departments,
+	}
+}
+
+func (d *department) Search(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) ([]*v1alpha1.Department, int64, error) {
+	ctx, span := startSpan(ctx, "department", "search")
+	defer span.End()
+
+	order, err := sortOrder(query.OrderBy, nil, departmentText, "id")
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+	req := blevesearch.NewSearchRequestOptions(departmentQuery(query), size, (page-1)*size, false)
+	req.SortByCustom(order)
+	deps, total, err := d.find(ctx, req)
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+	return deps, int64(total), nil
+}
+
+func (d *department) List(ctx context.Context, depIDs []interface{}) ([]*v1alpha1.Department, error) {
+	ctx, span := startSpan(ctx, "department", "list")
+	defer span.End()
+
+	ids := make([]string, 0, len(depIDs))
+	for _, id := range depIDs {
+		if id, ok := id.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []*v1alpha1.Department{}, nil
+	}
+	req := blevesearch.NewSearchRequestOptions(blevesearch.NewDocIDQuery(ids), len(ids), 0, false)
+	req.SortBy([]string{"_id"})
+	deps, _, err := d.find(ctx, req)
+	if err != nil {
+		return nil, fail(span, err)
+	}
+	return deps, nil
+}
+
+// departmentQuery query the departments like the query of the elasticsearch repo.
+func departmentQuery(q *v1alpha1.SearchDepartment) query.Query {
+	queries := make([]query.Query, 0)
+	if q.TenantID != "" {
+		queries = append(queries, term("tenantID", q.TenantID))
+	}
+	if q.Name != "" {
+		queries = append(queries, phrasePrefix("name", q.Name))
+	}
+	for _, attr := range q.Attr {
+		queries = append(queries, term("attr", strconv.Itoa(attr)))
+	}
+	return conjunction(queries)
+}
+
+func (d *department) find(ctx context.Context, req *blevesearch.SearchRequest) ([]*v1alpha1.Department, uint64, error) {
+	deps := make([]*v1alpha1.Department, 0)
+	total, err := do(ctx, d.index, req, func(source []byte) error {
+		dep := new(v1alpha1.Department)
+		if err := json.Unmarshal(source, dep); err != nil {
+			return err
+		}
+		deps = append(deps, dep)
+		return nil
+	})
+	return deps, total, err
+}
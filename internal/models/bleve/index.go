@@ -0,0 +1,237 @@
+package bleve
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	blevesearch "github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// sourceField stored json of the document, not indexed.
+const sourceField = "_source"
+
+// Config bleve backend config
+type Config struct {
+	// Path directory of the indices, created if not exist,
+	// the indices are kept in memory if not set.
+	Path string
+}
+
+// Index embedded bleve indices of the users and the departments.
+type Index struct {
+	users       blevesearch.Index
+	departments blevesearch.Index
+}
+
+// Open open the indices of the config, the indices are created if not exist.
+func Open(conf Config) (*Index, error) {
+	users, err := open(conf.Path, v1alpha1.UserIndex, userMapping())
+	if err != nil {
+		return nil, err
+	}
+	departments, err := open(conf.Path, v1alpha1.DepartmentIndex, departmentMapping())
+	if err != nil {
+		users.Close()
+		return nil, err
+	}
+	return &Index{
+		users:       users,
+		departments: departments,
+	}, nil
+}
+
+func open(dir, name string, m mapping.IndexMapping) (blevesearch.Index, error) {
+	if dir == "" {
+		return blevesearch.NewMemOnly(m)
+	}
+	path := filepath.Join(dir, name)
+	index, err := blevesearch.Open(path)
+	if err == blevesearch.ErrorIndexPathDoesNotExist {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		return blevesearch.New(path, m)
+	}
+	return index, err
+}
+
+// Close close the indices.
+func (i *Index) Close() error {
+	err := i.users.Close()
+	if derr := i.departments.Close(); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// PutUsers create or replace the users.
+func (i *Index) PutUsers(ctx context.Context, users ...*v1alpha1.User) error {
+	batch := i.users.NewBatch()
+	for _, user := range users {
+		doc, err := userDocument(user)
+		if err != nil {
+			return err
+		}
+		if err := batch.Index(user.ID, doc); err != nil {
+			return err
+		}
+	}
+	return i.users.Batch(batch)
+}
+
+// PutDepartments create or replace the departments.
+func (i *Index) PutDepartments(ctx context.Context, deps ...*v1alpha1.Department) error {
+	batch := i.departments.NewBatch()
+	for _, dep := range deps {
+		doc, err := departmentDocument(dep)
+		if err != nil {
+			return err
+		}
+		if err := batch.Index(dep.ID, doc); err != nil {
+			return err
+		}
+	}
+	return i.departments.Batch(batch)
+}
+
+func userDocument(user *v1alpha1.User) (map[string]interface{}, error) {
+	source, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := map[string][]string{}
+	for _, path := range user.Departments {
+		for _, dep := range path {
+			deps["id"] = append(deps["id"], dep.ID)
+			deps["name"] = append(deps["name"], dep.Name)
+		}
+	}
+	roles := map[string][]string{}
+	for _, role := range user.Roles {
+		roles["id"] = append(roles["id"], role.ID)
+		roles["name"] = append(roles["name"], role.Name)
+	}
+	leaders := map[string][]string{}
+	for _, path := range user.Leaders {
+		for _, leader := range path {
+			leaders["id"] = append(leaders["id"], leader.ID)
+		}
+	}
+
+	return map[string]interface{}{
+		"id":          user.ID,
+		"tenantID":    user.TenantID,
+		"name":        user.Name,
+		"phone":       user.Phone,
+		"email":       user.Email,
+		"jobNumber":   user.JobNumber,
+		"position":    user.Position,
+		"createdAt":   float64(user.CreatedAt),
+		"useStatus":   float64(user.UseStatus),
+		"gender":      float64(user.Gender),
+		"departments": deps,
+		"roles":       roles,
+		"leaders":     leaders,
+		sourceField:   string(source),
+	}, nil
+}
+
+func departmentDocument(dep *v1alpha1.Department) (map[string]interface{}, error) {
+	source, err := json.Marshal(dep)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"id":        dep.ID,
+		"tenantID":  dep.TenantID,
+		"name":      dep.Name,
+		"pid":       dep.PID,
+		"attr":      dep.Attr,
+		sourceField: string(source),
+	}, nil
+}
+
+func userMapping() mapping.IndexMapping {
+	doc := newDocumentMapping()
+	addKeyword(doc, "id", "tenantID")
+	addText(doc, "name", "phone", "email", "jobNumber", "position")
+	addNumeric(doc, "createdAt", "useStatus", "gender")
+	for _, relation := range []string{"departments", "roles", "leaders"} {
+		sub := newDocumentMapping()
+		addKeyword(sub, "id")
+		if relation != "leaders" {
+			addText(sub, "name")
+		}
+		doc.AddSubDocumentMapping(relation, sub)
+	}
+	return newIndexMapping(doc)
+}
+
+func departmentMapping() mapping.IndexMapping {
+	doc := newDocumentMapping()
+	addKeyword(doc, "id", "tenantID", "pid", "attr")
+	addText(doc, "name")
+	return newIndexMapping(doc)
+}
+
+func newIndexMapping(doc *mapping.DocumentMapping) mapping.IndexMapping {
+	m := blevesearch.NewIndexMapping()
+	m.DefaultMapping = doc
+	m.DefaultAnalyzer = standard.Name
+	return m
+}
+
+func newDocumentMapping() *mapping.DocumentMapping {
+	doc := blevesearch.NewDocumentStaticMapping()
+	source := blevesearch.NewTextFieldMapping()
+	source.Index = false
+	source.IncludeInAll = false
+	source.IncludeTermVectors = false
+	source.DocValues = false
+	doc.AddFieldMappingsAt(sourceField, source)
+	return doc
+}
+
+// addKeyword map the fields as not analyzed, for term queries and sorting.
+func addKeyword(doc *mapping.DocumentMapping, fields ...string) {
+	for _, field := range fields {
+		doc.AddFieldMappingsAt(field, keywordField(""))
+	}
+}
+
+// addText map the fields analyzed by the standard analyzer, for prefix queries,
+// the keyword sub field named field.keyword is for sorting like elasticsearch.
+func addText(doc *mapping.DocumentMapping, fields ...string) {
+	for _, field := range fields {
+		text := blevesearch.NewTextFieldMapping()
+		text.Analyzer = standard.Name
+		text.Store = false
+		text.IncludeTermVectors = true
+		doc.AddFieldMappingsAt(field, text, keywordField(field+".keyword"))
+	}
+}
+
+func addNumeric(doc *mapping.DocumentMapping, fields ...string) {
+	for _, field := range fields {
+		numeric := blevesearch.NewNumericFieldMapping()
+		numeric.Store = false
+		doc.AddFieldMappingsAt(field, numeric)
+	}
+}
+
+func keywordField(name string) *mapping.FieldMapping {
+	field := blevesearch.NewTextFieldMapping()
+	field.Name = name
+	field.Analyzer = keyword.Name
+	field.Store = false
+	field.IncludeInAll = false
+	field.IncludeTermVectors = false
+	return field
+}
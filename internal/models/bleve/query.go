@@ -0,0 +1,138 @@
+package bleve
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	blevesearch "github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/registry"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var analyzer *analysis.Analyzer
+
+func init() {
+	var err error
+	analyzer, err = registry.NewCache().AnalyzerNamed(standard.Name)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// tokenize split text by the analyzer of the text fields.
+func tokenize(text string) []string {
+	stream := analyzer.Analyze([]byte(text))
+	tokens := make([]string, 0, len(stream))
+	for _, token := range stream {
+		tokens = append(tokens, string(token.Term))
+	}
+	return tokens
+}
+
+// phrasePrefix like the match_phrase_prefix query of elasticsearch,
+// the tokens of text appear in order, the last token is matched as prefix.
+func phrasePrefix(field, text string) query.Query {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return blevesearch.NewMatchAllQuery()
+	}
+
+	last := blevesearch.NewPrefixQuery(tokens[len(tokens)-1])
+	last.SetField(field)
+	if len(tokens) == 1 {
+		return last
+	}
+	phrase := blevesearch.NewPhraseQuery(tokens[:len(tokens)-1], field)
+	return blevesearch.NewConjunctionQuery(phrase, last)
+}
+
+func term(field, value string) query.Query {
+	q := blevesearch.NewTermQuery(value)
+	q.SetField(field)
+	return q
+}
+
+func equal(field string, value float64) query.Query {
+	inclusive := true
+	q := blevesearch.NewNumericRangeInclusiveQuery(&value, &value, &inclusive, &inclusive)
+	q.SetField(field)
+	return q
+}
+
+// conjunction return the query matching all of queries, all documents if empty.
+func conjunction(queries []query.Query) query.Query {
+	if len(queries) == 0 {
+		return blevesearch.NewMatchAllQuery()
+	}
+	return blevesearch.NewConjunctionQuery(queries...)
+}
+
+// sortOrder return the sort order of the fields prefixed by - for descending,
+// the text fields are sorted by their keyword sub field. The documents missing
+// the field are last, the tie breakers are ascending.
+func sortOrder(fields []string, numeric, text map[string]bool, tieBreakers ...string) (search.SortOrder, error) {
+	all := make([]string, 0, len(fields)+len(tieBreakers))
+	all = append(append(all, fields...), tieBreakers...)
+	order := make(search.SortOrder, 0, len(all))
+	for _, field := range all {
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimSuffix(strings.TrimPrefix(field, "-"), ".keyword")
+		switch {
+		case field == "id":
+			order = append(order, &search.SortDocID{Desc: desc})
+		case numeric[field]:
+			order = append(order, &search.SortField{
+				Field: field, Desc: desc, Type: search.SortFieldAsNumber, Missing: search.SortFieldMissingLast,
+			})
+		case text[field]:
+			order = append(order, &search.SortField{
+				Field: field + ".keyword", Desc: desc, Type: search.SortFieldAsString, Missing: search.SortFieldMissingLast,
+			})
+		default:
+			return nil, errdefs.Wrap(errdefs.InvalidArgument, fmt.Errorf("unknown order field %q", field))
+		}
+	}
+	return order, nil
+}
+
+// do run the request on index, the sources of the hits are decoded by decode.
+func do(ctx context.Context, index blevesearch.Index, req *blevesearch.SearchRequest,
+	decode func(source []byte) error) (uint64, error) {
+	req.Fields = []string{sourceField}
+	result, err := index.SearchInContext(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	for _, hit := range result.Hits {
+		source, _ := hit.Fields[sourceField].(string)
+		if err := decode([]byte(source)); err != nil {
+			return 0, err
+		}
+	}
+	return result.Total, nil
+}
+
+// startSpan start the span of the search.
+func startSpan(ctx context.Context, repo, method string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "bleve."+repo+"."+method,
+		semconv.DBSystemKey.String("bleve"),
+		semconv.DBNameKey.String(repo),
+		semconv.DBOperationKey.String(method),
+	)
+}
+
+// fail classify err and record it on the span, the errors of the embedded
+// index are internal errors.
+func fail(span trace.Span, err error) error {
+	e := errdefs.From(err)
+	tracing.Error(span, e)
+	return e
+}
@@ -0,0 +1,146 @@
+package bleve
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	blevesearch "github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+var (
+	userNumeric = map[string]bool{"createdAt": true, "useStatus": true, "gender": true}
+	userText    = map[string]bool{"name": true, "phone": true, "email": true, "jobNumber": true, "position": true}
+)
+
+type user struct {
+	index blevesearch.Index
+}
+
+// NewUser return the user repo of the index.
+func NewUser(index *Index) models.UserRepo {
+	return &user{
+		index: index.users,
+	}
+}
+
+func (u *user) Get(ctx context.Context, userID string) (*v1alpha1.User, error) {
+	ctx, span := startSpan(ctx, "user", "get")
+	defer span.End()
+
+	users, _, err := u.find(ctx, blevesearch.NewSearchRequestOptions(
+		blevesearch.NewDocIDQuery([]string{userID}), 1, 0, false))
+	if err != nil {
+		return nil, fail(span, err)
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return users[0], nil
+}
+
+func (u *user) List(ctx context.Context, userIDs []interface{}) ([]*v1alpha1.User, error) {
+	ctx, span := startSpan(ctx, "user", "list")
+	defer span.End()
+
+	ids := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id, ok := id.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []*v1alpha1.User{}, nil
+	}
+	req := blevesearch.NewSearchRequestOptions(blevesearch.NewDocIDQuery(ids), len(ids), 0, false)
+	req.SortBy([]string{"_id"})
+	users, _, err := u.find(ctx, req)
+	if err != nil {
+		return nil, fail(span, err)
+	}
+	return users, nil
+}
+
+func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, size int) ([]*v1alpha1.User, int64, error) {
+	ctx, span := startSpan(ctx, "user", "search")
+	defer span.End()
+
+	order, err := sortOrder(query.OrderBy, userNumeric, userText, "name", "id")
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+	req := blevesearch.NewSearchRequestOptions(userQuery(query), size, (page-1)*size, false)
+	req.SortByCustom(order)
+	users, total, err := u.find(ctx, req)
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+	return users, int64(total), nil
+}
+
+// userQuery query the users like the query of the elasticsearch repo.
+func userQuery(q *v1alpha1.SearchUser) query.Query {
+	queries := make([]query.Query, 0)
+	if q.TenantID != "" {
+		queries = append(queries, term("tenantID", q.TenantID))
+	}
+	if q.DepartmentID != "" {
+		queries = append(queries, term("departments.id", q.DepartmentID))
+	}
+	if q.DepartmentName != "" {
+		queries = append(queries, phrasePrefix("departments.name", q.DepartmentName))
+	}
+	if q.RoleID != "" {
+		queries = append(queries, term("roles.id", q.RoleID))
+	}
+	if q.RoleName != "" {
+		queries = append(queries, phrasePrefix("roles.name", q.RoleName))
+	}
+	if q.LeaderID != "" {
+		queries = append(queries, term("leaders.id", q.LeaderID))
+	}
+	if q.UseStatus != 0 {
+		queries = append(queries, equal("useStatus", float64(q.UseStatus)))
+	}
+	if q.Gender != "" {
+		// gender is a single digit, the prefix matches the whole value.
+		gender, err := strconv.Atoi(q.Gender)
+		if err != nil {
+			queries = append(queries, blevesearch.NewMatchNoneQuery())
+		} else {
+			queries = append(queries, equal("gender", float64(gender)))
+		}
+	}
+
+	prefixes := []struct {
+		field, query string
+	}{
+		{"name", q.Name},
+		{"phone", q.Phone},
+		{"email", q.Email},
+		{"jobNumber", q.JobNumber},
+		{"position", q.Position},
+	}
+	for _, prefix := range prefixes {
+		if prefix.query != "" {
+			queries = append(queries, phrasePrefix(prefix.field, prefix.query))
+		}
+	}
+	return conjunction(queries)
+}
+
+func (u *user) find(ctx context.Context, req *blevesearch.SearchRequest) ([]*v1alpha1.User, uint64, error) {
+	users := make([]*v1alpha1.User, 0)
+	total, err := do(ctx, u.index, req, func(source []byte) error {
+		user := new(v1alpha1.User)
+		if err := json.Unmarshal(source, user); err != nil {
+			return err
+		}
+		users = append(users, user)
+		return nil
+	})
+	return users, total, err
+}
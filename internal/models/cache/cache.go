@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// backends
+const (
+	// BackendNone the cache is disabled.
+	BackendNone = ""
+	// BackendMemory in-memory LRU of each instance.
+	BackendMemory = "memory"
+	// BackendRedis redis shared by the instances.
+	BackendRedis = "redis"
+)
+
+// kinds of the cached repos
+const (
+	KindUser       = "user"
+	KindDepartment = "department"
+)
+
+const keyPrefix = "search:cache:"
+
+// Config cache config
+type Config struct {
+	// Backend memory, redis or empty for no cache
+	Backend string
+	// TTL time to live of the entries, default 1m
+	TTL time.Duration
+	// Size max entries of the memory backend, default 10000
+	Size  int
+	Redis RedisConfig
+}
+
+// Store backend of the cache.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Counter return the counter of key, zero if not exist.
+	Counter(ctx context.Context, key string) (int64, error)
+	// Incr increase the counter of key, counters never expire.
+	Incr(ctx context.Context, key string) (int64, error)
+	Close() error
+}
+
+// Cache results of the repos, keyed by kind, tenant, method and arguments.
+// Invalidate bumps the generation of the tenant, which is part of the keys,
+// so the stale entries are never read again and expire by TTL.
+type Cache struct {
+	log   logr.Logger
+	store Store
+	ttl   time.Duration
+}
+
+// New return the cache of the config, nil if the cache is disabled.
+func New(ctx context.Context, conf Config) (*Cache, error) {
+	var store Store
+	switch conf.Backend {
+	case BackendNone:
+		return nil, nil
+	case BackendMemory:
+		size := conf.Size
+		if size <= 0 {
+			size = 10000
+		}
+		store = NewMemory(size)
+	case BackendRedis:
+		client := newRedisClient(conf.Redis)
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, err
+		}
+		store = NewRedis(client)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", conf.Backend)
+	}
+
+	return NewWithStore(ctx, store, conf.TTL), nil
+}
+
+// NewWithStore return the cache backed by store, ttl is 1m if not positive.
+func NewWithStore(ctx context.Context, store Store, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &Cache{
+		log:   util.LoggerFromContext(ctx).WithName("cache"),
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Invalidate invalidate the entries of the tenant of kind, it is called
+// after the documents of the tenant are written. The entries of the queries
+// across tenants are invalidated too.
+func (c *Cache) Invalidate(ctx context.Context, kind, tenantID string) error {
+	tenants := []string{""}
+	if tenantID != "" {
+		tenants = append(tenants, tenantID)
+	}
+	for _, tenantID := range tenants {
+		if _, err := c.store.Incr(ctx, generationKey(kind, tenantID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close close the store.
+func (c *Cache) Close() error {
+	return c.store.Close()
+}
+
+// key return the key of the method called with args,
+// empty if the generation is not available.
+func (c *Cache) key(ctx context.Context, kind, tenantID, method string, args ...interface{}) string {
+	generation, err := c.store.Counter(ctx, generationKey(kind, tenantID))
+	if err != nil {
+		util.LoggerWithRequest(ctx, c.log).Error(err, "get generation", "kind", kind)
+		return ""
+	}
+
+	body, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return keyPrefix + kind + ":" + tenantID + ":" + strconv.FormatInt(generation, 10) + ":" +
+		method + ":" + hex.EncodeToString(sum[:])
+}
+
+// load decode the entry of key into dst, false if miss.
+func (c *Cache) load(ctx context.Context, key string, dst interface{}) bool {
+	if key == "" {
+		return false
+	}
+	value, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		util.LoggerWithRequest(ctx, c.log).Error(err, "get")
+		return false
+	}
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(value, dst) == nil
+}
+
+func (c *Cache) save(ctx context.Context, key string, value interface{}) {
+	if key == "" {
+		return
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := c.store.Set(ctx, key, body, c.ttl); err != nil {
+		util.LoggerWithRequest(ctx, c.log).Error(err, "set")
+	}
+}
+
+func generationKey(kind, tenantID string) string {
+	return keyPrefix + "generation:" + kind + ":" + tenantID
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+// memory in-memory LRU store with TTL.
+type memory struct {
+	mu      sync.Mutex
+	size    int
+	ll      *list.List
+	entries map[string]*list.Element
+	// counters at most size, they are dropped together once full.
+	// floor is above every generation returned before the drop,
+	// so the dropped generations never come back with the stale entries.
+	counters map[string]int64
+	floor    int64
+}
+
+// NewMemory return the in-memory store keeping at most size entries.
+func NewMemory(size int) Store {
+	return &memory{
+		size:     size,
+		ll:       list.New(),
+		entries:  make(map[string]*list.Element),
+		counters: make(map[string]int64),
+	}
+}
+
+func (m *memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := elem.Value.(*entry)
+	if time.Now().After(e.expireAt) {
+		m.remove(elem)
+		return nil, false, nil
+	}
+	m.ll.MoveToFront(elem)
+	return e.value, true, nil
+}
+
+func (m *memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expireAt := time.Now().Add(ttl)
+	if elem, ok := m.entries[key]; ok {
+		e := elem.Value.(*entry)
+		e.value, e.expireAt = value, expireAt
+		m.ll.MoveToFront(elem)
+		return nil
+	}
+
+	m.entries[key] = m.ll.PushFront(&entry{
+		key:      key,
+		value:    value,
+		expireAt: expireAt,
+	})
+	for m.size > 0 && m.ll.Len() > m.size {
+		m.remove(m.ll.Back())
+	}
+	return nil
+}
+
+func (m *memory) Counter(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n, ok := m.counters[key]; ok {
+		return n, nil
+	}
+	return m.floor, nil
+}
+
+func (m *memory) Incr(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, ok := m.counters[key]
+	if !ok {
+		if m.size > 0 && len(m.counters) >= m.size {
+			m.dropCounters()
+		}
+		n = m.floor
+	}
+	m.counters[key] = n + 1
+	return n + 1, nil
+}
+
+// dropCounters drop the counters, raising floor above all of them.
+func (m *memory) dropCounters() {
+	for _, n := range m.counters {
+		if n > m.floor {
+			m.floor = n
+		}
+	}
+	m.floor++
+	m.counters = make(map[string]int64)
+}
+
+func (m *memory) Close() error {
+	return nil
+}
+
+func (m *memory) remove(elem *list.Element) {
+	m.ll.Remove(elem)
+	delete(m.entries, elem.Value.(*entry).key)
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryCounters(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2).(*memory)
+
+	// the generation of each key only increases, even if its counter is dropped.
+	last := make(map[string]int64)
+	for _, key := range []string{"a", "a", "b", "c", "a", "d", "b", "a"} {
+		n, err := m.Incr(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.counters) > 2 {
+			t.Fatalf("incr %s: %d counters over the size", key, len(m.counters))
+		}
+		for k, prev := range last {
+			current, err := m.Counter(ctx, k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current < prev {
+				t.Errorf("after incr %s: generation of %s goes back from %d to %d", key, k, prev, current)
+			}
+		}
+		if prev, ok := last[key]; ok && n <= prev {
+			t.Errorf("incr %s: generation %d not above %d", key, n, prev)
+		}
+		last[key] = n
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisConfig redis config
+type RedisConfig struct {
+	// Addrs a single address for the standalone server,
+	// multiple addresses for the cluster
+	Addrs    []string
+	Username string
+	Password string
+	DB       int
+}
+
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedis return the store backed by the redis client,
+// which can be replaced by any redis compatible server.
+func NewRedis(client redis.UniversalClient) Store {
+	return &redisStore{
+		client: client,
+	}
+}
+
+func newRedisClient(conf RedisConfig) redis.UniversalClient {
+	return redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    conf.Addrs,
+		Username: conf.Username,
+		Password: conf.Password,
+		DB:       conf.DB,
+	})
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *redisStore) Counter(ctx context.Context, key string) (int64, error) {
+	counter, err := r.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return counter, err
+}
+
+func (r *redisStore) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, key).Result()
+}
+
+func (r *redisStore) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+type user struct {
+	repo  models.UserRepo
+	cache *Cache
+}
+
+// NewUser return the user repo caching the results of repo.
+func NewUser(repo models.UserRepo, cache *Cache) models.UserRepo {
+	return &user{
+		repo:  repo,
+		cache: cache,
+	}
+}
+
+func (u *user) Get(ctx context.Context, userID string) (*v1alpha1.User, error) {
+	key := u.cache.key(ctx, KindUser, models.TenantFromContext(ctx), "get", userID)
+	var user *v1alpha1.User
+	if u.cache.load(ctx, key, &user) {
+		metrics.ObserveCache(KindUser, "get", true)
+		return user, nil
+	}
+	metrics.ObserveCache(KindUser, "get", false)
+
+	partialCtx, meta := models.WithMeta(ctx)
+	user, err := u.repo.Get(partialCtx, userID)
+	if err == nil && complete(ctx, meta) {
+		u.cache.save(ctx, key, user)
+	}
+	return user, err
+}
+
+func (u *user) List(ctx context.Context, userIDs []interface{}) ([]*v1alpha1.User, error) {
+	key := u.cache.key(ctx, KindUser, models.TenantFromContext(ctx), "list", userIDs)
+	var users []*v1alpha1.User
+	if u.cache.load(ctx, key, &users) {
+		metrics.ObserveCache(KindUser, "list", true)
+		return users, nil
+	}
+	metrics.ObserveCache(KindUser, "list", false)
+
+	partialCtx, meta := models.WithMeta(ctx)
+	users, err := u.repo.List(partialCtx, userIDs)
+	if err == nil && complete(ctx, meta) {
+		u.cache.save(ctx, key, users)
+	}
+	return users, err
+}
+
+type searchResult struct {
+	Users       []*v1alpha1.User       `json:"users,omitempty"`
+	Departments []*v1alpha1.Department `json:"departments,omitempty"`
+	Total       int64                  `json:"total"`
+}
+
+func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, size int) ([]*v1alpha1.User, int64, error) {
+	key := u.cache.key(ctx, KindUser, query.TenantID, "search", query, page, size)
+	var result searchResult
+	if u.cache.load(ctx, key, &result) {
+		metrics.ObserveCache(KindUser, "search", true)
+		return result.Users, result.Total, nil
+	}
+	metrics.ObserveCache(KindUser, "search", false)
+
+	partialCtx, meta := models.WithMeta(ctx)
+	users, total, err := u.repo.Search(partialCtx, query, page, size)
+	if err == nil && complete(ctx, meta) {
+		u.cache.save(ctx, key, searchResult{Users: users, Total: total})
+	}
+	return users, total, err
+}
+
+type department struct {
+	repo  models.DepartmentRepo
+	cache *Cache
+}
+
+// NewDepartment return the department repo caching the results of repo.
+func NewDepartment(repo models.DepartmentRepo, cache *Cache) models.DepartmentRepo {
+	return &department{
+		repo:  repo,
+		cache: cache,
+	}
+}
+
+func (d *department) Search(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) ([]*v1alpha1.Department, int64, error) {
+	key := d.cache.key(ctx, KindDepartment, query.TenantID, "search", query, page, size)
+	var result searchResult
+	if d.cache.load(ctx, key, &result) {
+		metrics.ObserveCache(KindDepartment, "search", true)
+		return result.Departments, result.Total, nil
+	}
+	metrics.ObserveCache(KindDepartment, "search", false)
+
+	partialCtx, meta := models.WithMeta(ctx)
+	deps, total, err := d.repo.Search(partialCtx, query, page, size)
+	if err == nil && complete(ctx, meta) {
+		d.cache.save(ctx, key, searchResult{Departments: deps, Total: total})
+	}
+	return deps, total, err
+}
+
+func (d *department) List(ctx context.Context, depIDs []interface{}) ([]*v1alpha1.Department, error) {
+	key := d.cache.key(ctx, KindDepartment, models.TenantFromContext(ctx), "list", depIDs)
+	var deps []*v1alpha1.Department
+	if d.cache.load(ctx, key, &deps) {
+		metrics.ObserveCache(KindDepartment, "list", true)
+		return deps, nil
+	}
+	metrics.ObserveCache(KindDepartment, "list", false)
+
+	partialCtx, meta := models.WithMeta(ctx)
+	deps, err := d.repo.List(partialCtx, depIDs)
+	if err == nil && complete(ctx, meta) {
+		d.cache.save(ctx, key, deps)
+	}
+	return deps, err
+}
+
+// complete return false if the results are partial or stale, which are not cached,
+// the flags are passed to the meta of ctx.
+func complete(ctx context.Context, meta *models.Meta) bool {
+	ok := true
+	if meta.Partial() {
+		models.MetaFromContext(ctx).SetPartial()
+		ok = false
+	}
+	if meta.Stale() {
+		models.MetaFromContext(ctx).SetStale()
+		ok = false
+	}
+	return ok
+}
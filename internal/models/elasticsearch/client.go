@@ -0,0 +1,32 @@
+package elasticsearch
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+)
+
+// Config elasticsearch config
+type Config struct {
+	// Engine es7, es8 or opensearch, default es7
+	Engine string
+	Host   []string
+	// Log log the requests
+	Log      bool
+	Username string
+	Password string
+	// Index index names of the repos
+	Index IndexConfig
+}
+
+// NewEngine return the search engine of the config, the cluster must be reachable.
+func NewEngine(ctx context.Context, conf *Config, log logr.Logger) (engine.Engine, error) {
+	return engine.New(ctx, engine.Config{
+		Kind:     conf.Engine,
+		Hosts:    conf.Host,
+		Username: conf.Username,
+		Password: conf.Password,
+		Trace:    conf.Log,
+	}, log)
+}
@@ -6,29 +6,36 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/olivere/elastic/v7"
 	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
 	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
 	"github.com/quanxiang-cloud/search/pkg/util"
 	"strings"
+	"time"
 )
 
 type department struct {
 	log    logr.Logger
-	client *elastic.Client
+	engine engine.Engine
+
+	searchOptions
+	indexer
 }
 
-func NewDepartment(ctx context.Context, client *elastic.Client) models.DepartmentRepo {
+func NewDepartment(ctx context.Context, e engine.Engine, opts ...Option) models.DepartmentRepo {
+	o := newSearchOptions(opts...)
 	return &department{
-		log:    util.LoggerFromContext(ctx).WithName("department"),
-		client: client,
+		log:           util.LoggerFromContext(ctx).WithName("department"),
+		engine:        e,
+		searchOptions: o,
+		indexer:       newIndexer(o.index, o.index.Department, v1alpha1.DepartmentIndex),
 	}
 }
 
-func (u *department) index() string {
-	return "department"
-}
-
 func (u *department) Search(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) ([]*v1alpha1.Department, int64, error) {
-	ql := u.client.Search().Index(u.index())
+	ctx, span := startSpan(ctx, "department", "search", u.shared)
+	defer span.End()
+
+	source := u.apply(ctx, elastic.NewSearchSource())
 
 	mustQuery := make([]elastic.Query, 0)
 
@@ -45,60 +52,68 @@ func (u *department) Search(ctx context.Context, query *v1alpha1.SearchDepartmen
 			mustQuery = append(mustQuery, elastic.NewTermQuery("attr", query.Attr[k]))
 		}
 	}
-	ql = ql.Query(elastic.NewBoolQuery().Must(mustQuery...))
+	source = source.Query(elastic.NewBoolQuery().Must(mustQuery...))
 
 	for _, orderBy := range query.OrderBy {
 		if strings.HasPrefix(orderBy, "-") {
-			ql = ql.Sort(orderBy[1:], true)
+			source = source.Sort(orderBy[1:], true)
 			continue
 		}
-		ql = ql.Sort(orderBy, false)
+		source = source.Sort(orderBy, false)
 	}
 
-	ql = ql.Sort("id.keyword", true)
+	source = source.Sort("id.keyword", true)
 
-	result, err := ql.From((page - 1) * size).Size(size).
-		Do(ctx)
+	source = source.From((page - 1) * size).Size(size)
+	start := time.Now()
+	result, err := u.search(ctx, u.engine, query.TenantID, source)
+	u.observe(ctx, u.log, "department", "search", start, source, result, err)
 
 	if err != nil {
-		u.log.Error(err, "department search")
-		return nil, 0, err
+		util.LoggerWithRequest(ctx, u.log).Error(err, "department search")
+		return nil, 0, wrapError(err)
 	}
+	checkPartial(ctx, result)
 
 	deps := make([]*v1alpha1.Department, 0, size)
-	for _, hit := range result.Hits.Hits {
+	for _, hit := range result.Hits {
 		dep := new(v1alpha1.Department)
-		err := json.Unmarshal(hit.Source, dep)
+		err := json.Unmarshal(hit, dep)
 		if err != nil {
 			return nil, 0, err
 		}
 		deps = append(deps, dep)
 	}
 
-	return deps, result.Hits.TotalHits.Value, nil
+	return deps, result.Total, nil
 }
 
 func (u *department) List(ctx context.Context, depIDs []interface{}) ([]*v1alpha1.Department, error) {
+	ctx, span := startSpan(ctx, "department", "list", u.shared)
+	defer span.End()
+
 	var size = 0
 	if len(depIDs) > 100 {
 		size = 99
 	} else {
 		size = len(depIDs)
 	}
-	result, err := u.client.Search().
-		Index(u.index()).
+	source := u.apply(ctx, elastic.NewSearchSource().
 		Query(
 			elastic.NewTermsQuery("id.keyword", depIDs...),
-		).From(0).Size(size).
-		Do(ctx)
+		).From(0).Size(size))
+	start := time.Now()
+	result, err := u.search(ctx, u.engine, models.TenantFromContext(ctx), source)
+	u.observe(ctx, u.log, "department", "list", start, source, result, err)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
+	checkPartial(ctx, result)
 
 	deps := make([]*v1alpha1.Department, 0, len(depIDs))
-	for _, hit := range result.Hits.Hits {
+	for _, hit := range result.Hits {
 		dep := new(v1alpha1.Department)
-		err := json.Unmarshal(hit.Source, dep)
+		err := json.Unmarshal(hit, dep)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,361 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+// fakeCluster serve the subset of the REST API used by the engines,
+// answering like the cluster of kind.
+type fakeCluster struct {
+	*httptest.Server
+	kind string
+	// malformed answer the searches by a truncated body
+	malformed bool
+
+	mu       sync.Mutex
+	searches []*http.Request
+	bodies   []map[string]interface{}
+}
+
+func newFakeCluster(t *testing.T, kind string) *fakeCluster {
+	t.Helper()
+	f := &fakeCluster{kind: kind}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeCluster) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if f.kind != KindOpenSearch {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "":
+		version := map[string]interface{}{"number": "7.17.0"}
+		switch f.kind {
+		case KindES8:
+			version = map[string]interface{}{"number": "8.11.0"}
+		case KindOpenSearch:
+			version = map[string]interface{}{"number": "2.11.0", "distribution": "opensearch"}
+		}
+		f.write(w, http.StatusOK, map[string]interface{}{"name": "fake", "version": version})
+	case path == "_nodes/http":
+		f.write(w, http.StatusOK, map[string]interface{}{
+			"nodes": map[string]interface{}{
+				"fake": map[string]interface{}{
+					"name": "fake",
+					"http": map[string]interface{}{"publish_address": strings.TrimPrefix(f.URL, "http://")},
+				},
+			},
+		})
+	case path == "_cluster/health":
+		f.write(w, http.StatusOK, map[string]interface{}{"cluster_name": "fake", "status": "green"})
+	case strings.HasSuffix(path, "/_search"):
+		f.search(w, r, strings.TrimSuffix(path, "/_search"))
+	case strings.Contains(path, "/_mapping"):
+		index := path[:strings.Index(path, "/_mapping")]
+		if index != "user" {
+			f.write(w, http.StatusNotFound, indexNotFound(index))
+			return
+		}
+		f.write(w, http.StatusOK, map[string]interface{}{
+			"user": map[string]interface{}{
+				"mappings": map[string]interface{}{"_meta": map[string]interface{}{"version": "1"}},
+			},
+		})
+	case r.Method == http.MethodHead:
+		if path != "user" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		f.write(w, http.StatusNotFound, indexNotFound(path))
+	}
+}
+
+func (f *fakeCluster) search(w http.ResponseWriter, r *http.Request, indices string) {
+	body := map[string]interface{}{}
+	raw, _ := ioutil.ReadAll(r.Body)
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &body)
+	}
+	f.mu.Lock()
+	f.searches = append(f.searches, r)
+	f.bodies = append(f.bodies, body)
+	f.mu.Unlock()
+
+	if f.malformed {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"hits":`))
+		return
+	}
+	if strings.Contains(indices, "broken") {
+		f.write(w, http.StatusBadRequest, map[string]interface{}{
+			"error":  map[string]interface{}{"type": "parsing_exception", "reason": "unknown query [bogus]"},
+			"status": http.StatusBadRequest,
+		})
+		return
+	}
+
+	// opensearch may answer the total as a number, e.g. rest_total_hits_as_int
+	var total interface{} = map[string]interface{}{"value": 2, "relation": "eq"}
+	if f.kind == KindOpenSearch {
+		total = 2
+	}
+	f.write(w, http.StatusOK, map[string]interface{}{
+		"took":      1,
+		"timed_out": true,
+		"_shards":   map[string]interface{}{"total": 2, "successful": 1, "failed": 1},
+		"hits": map[string]interface{}{
+			"total": total,
+			"hits": []map[string]interface{}{
+				{"_index": "user", "_id": "u1", "_source": map[string]interface{}{"id": "u1"}},
+				{"_index": "user", "_id": "u2", "_source": map[string]interface{}{"id": "u2"}},
+			},
+		},
+	})
+}
+
+func (f *fakeCluster) lastSearch() (*http.Request, map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.searches) == 0 {
+		return nil, nil
+	}
+	return f.searches[len(f.searches)-1], f.bodies[len(f.bodies)-1]
+}
+
+func (f *fakeCluster) searchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.searches)
+}
+
+func (f *fakeCluster) write(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func indexNotFound(index string) map[string]interface{} {
+	return map[string]interface{}{
+		"error": map[string]interface{}{
+			"type":   "index_not_found_exception",
+			"reason": fmt.Sprintf("no such index [%s]", index),
+		},
+		"status": http.StatusNotFound,
+	}
+}
+
+// TestContract run the same contract against the engines of all kinds.
+func TestContract(t *testing.T) {
+	for _, kind := range []string{KindES7, KindES8, KindOpenSearch} {
+		kind := kind
+		t.Run(kind, func(t *testing.T) {
+			ctx := context.Background()
+			cluster := newFakeCluster(t, kind)
+			e, err := New(ctx, Config{Kind: kind, Hosts: []string{cluster.URL}}, logr.Discard())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Stop()
+
+			t.Run("search", func(t *testing.T) {
+				body := map[string]interface{}{
+					"query": map[string]interface{}{"term": map[string]interface{}{"tenantID": "t1"}},
+					"size":  float64(2),
+				}
+				result, err := e.Search(ctx, &SearchRequest{
+					Indices: []string{"user", "user-t1"},
+					Routing: "t1",
+					Body:    body,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req, got := cluster.lastSearch()
+				if req == nil {
+					t.Fatal("no search received")
+				}
+				if req.URL.Path != "/user,user-t1/_search" {
+					t.Errorf("want path /user,user-t1/_search, got %s", req.URL.Path)
+				}
+				if routing := req.URL.Query().Get("routing"); routing != "t1" {
+					t.Errorf("want routing t1, got %q", routing)
+				}
+				if !reflect.DeepEqual(got, body) {
+					t.Errorf("want body %v, got %v", body, got)
+				}
+				if kind == KindES8 && !strings.Contains(req.Header.Get("Content-Type"), "compatible-with=8") {
+					t.Errorf("want compatible-with=8 content type, got %q", req.Header.Get("Content-Type"))
+				}
+
+				if result.Total != 2 {
+					t.Errorf("want total 2, got %d", result.Total)
+				}
+				ids := make([]string, 0, len(result.Hits))
+				for _, hit := range result.Hits {
+					doc := struct {
+						ID string `json:"id"`
+					}{}
+					if err := json.Unmarshal(hit, &doc); err != nil {
+						t.Fatal(err)
+					}
+					ids = append(ids, doc.ID)
+				}
+				if !reflect.DeepEqual(ids, []string{"u1", "u2"}) {
+					t.Errorf("want hits [u1 u2], got %v", ids)
+				}
+				if !result.TimedOut || result.FailedShards != 1 {
+					t.Errorf("want timed out with 1 failed shard, got %v and %d", result.TimedOut, result.FailedShards)
+				}
+			})
+
+			t.Run("search without routing", func(t *testing.T) {
+				if _, err := e.Search(ctx, &SearchRequest{Indices: []string{"user"}}); err != nil {
+					t.Fatal(err)
+				}
+				req, _ := cluster.lastSearch()
+				if _, ok := req.URL.Query()["routing"]; ok {
+					t.Errorf("want no routing, got %q", req.URL.RawQuery)
+				}
+			})
+
+			t.Run("search error", func(t *testing.T) {
+				_, err := e.Search(ctx, &SearchRequest{Indices: []string{"broken"}})
+				var engineErr *Error
+				if !errors.As(err, &engineErr) {
+					t.Fatalf("want *Error, got %v", err)
+				}
+				if engineErr.Status != http.StatusBadRequest || engineErr.Type != "parsing_exception" {
+					t.Errorf("want 400 parsing_exception, got %d %s", engineErr.Status, engineErr.Type)
+				}
+			})
+
+			t.Run("health", func(t *testing.T) {
+				status, err := e.Health(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if status != "green" {
+					t.Errorf("want green, got %s", status)
+				}
+			})
+
+			t.Run("index exists", func(t *testing.T) {
+				for index, want := range map[string]bool{"user": true, "missing": false} {
+					exists, err := e.IndexExists(ctx, index)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if exists != want {
+						t.Errorf("want %s exists %v, got %v", index, want, exists)
+					}
+				}
+			})
+
+			t.Run("mappings", func(t *testing.T) {
+				mappings, err := e.Mappings(ctx, "user")
+				if err != nil {
+					t.Fatal(err)
+				}
+				meta, _ := mappings["user"]["_meta"].(map[string]interface{})
+				if meta["version"] != "1" {
+					t.Errorf("want mapping version 1, got %v", mappings)
+				}
+			})
+
+			t.Run("unavailable", func(t *testing.T) {
+				cluster.Close()
+				_, err := e.Search(ctx, &SearchRequest{Indices: []string{"user"}})
+				if !errors.Is(err, ErrUnavailable) {
+					t.Errorf("want ErrUnavailable, got %v", err)
+				}
+			})
+		})
+	}
+}
+
+// TestProductCheck the engines refuse the clusters of the other products.
+func TestProductCheck(t *testing.T) {
+	tests := []struct {
+		kind    string
+		cluster string
+	}{
+		{kind: KindES8, cluster: KindES7},
+		{kind: KindES8, cluster: KindOpenSearch},
+		{kind: KindOpenSearch, cluster: KindES8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind+" on "+tt.cluster, func(t *testing.T) {
+			cluster := newFakeCluster(t, tt.cluster)
+			e, err := New(context.Background(), Config{Kind: tt.kind, Hosts: []string{cluster.URL}}, logr.Discard())
+			if err == nil {
+				e.Stop()
+				t.Fatal("want error")
+			}
+		})
+	}
+}
+
+// TestFailover the http engines only try the next host if the host is not reachable.
+func TestFailover(t *testing.T) {
+	ctx := context.Background()
+	for _, kind := range []string{KindES8, KindOpenSearch} {
+		t.Run(kind, func(t *testing.T) {
+			down := newFakeCluster(t, kind)
+			down.Close()
+			healthy := newFakeCluster(t, kind)
+			e, err := New(ctx, Config{Kind: kind, Hosts: []string{down.URL, healthy.URL}}, logr.Discard())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Stop()
+			for i := 0; i < 2; i++ {
+				if _, err := e.Search(ctx, &SearchRequest{Indices: []string{"user"}}); err != nil {
+					t.Errorf("unreachable host is not skipped: %v", err)
+				}
+			}
+
+			malformed := newFakeCluster(t, kind)
+			malformed.malformed = true
+			e, err = New(ctx, Config{Kind: kind, Hosts: []string{malformed.URL, healthy.URL}}, logr.Discard())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Stop()
+			searches := healthy.searchCount()
+			var failed int
+			for i := 0; i < 2; i++ {
+				_, err := e.Search(ctx, &SearchRequest{Indices: []string{"user"}})
+				if errors.Is(err, ErrUnavailable) {
+					t.Errorf("malformed body must not be unavailable: %v", err)
+				}
+				if err != nil {
+					failed++
+				}
+			}
+			// one search of each host, the failed one is not retried on the next host
+			if failed != 1 || malformed.searchCount() != 1 || healthy.searchCount()-searches != 1 {
+				t.Errorf("failed %d, searches %d and %d, expect one of each",
+					failed, malformed.searchCount(), healthy.searchCount()-searches)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package engine abstracts the search engines speaking the query DSL of
+// elasticsearch, the repos build the DSL and the engines transport it.
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// kinds of the engines
+const (
+	KindES7        = "es7"
+	KindES8        = "es8"
+	KindOpenSearch = "opensearch"
+)
+
+// ErrUnavailable no node of the cluster is reachable.
+var ErrUnavailable = errors.New("no search engine node available")
+
+// Engine search engine of the repos.
+type Engine interface {
+	// Search run the search of req.
+	Search(ctx context.Context, req *SearchRequest) (*SearchResult, error)
+	// Health return the status of the cluster, green, yellow or red.
+	Health(ctx context.Context) (string, error)
+	// IndexExists return true if the index exists.
+	IndexExists(ctx context.Context, index string) (bool, error)
+	// Mappings return the mappings of the indices matched by index, keyed by index name.
+	Mappings(ctx context.Context, index string) (map[string]map[string]interface{}, error)
+	// Stop release the resources, the in-flight requests are not interrupted.
+	Stop()
+}
+
+// SearchRequest search of the indices.
+type SearchRequest struct {
+	Indices []string
+	// Routing routing value of the shards, empty for all shards
+	Routing string
+	// Body the DSL, marshaled as json
+	Body interface{}
+}
+
+// SearchResult result of the search.
+type SearchResult struct {
+	// Total total hits of the query
+	Total int64
+	// Hits sources of the hits
+	Hits            []json.RawMessage
+	TimedOut        bool
+	TerminatedEarly bool
+	// FailedShards number of the shards failed
+	FailedShards int
+}
+
+// Error error response of the engine.
+type Error struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("search engine: %d %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// Config config of the engines.
+type Config struct {
+	// Kind es7, es8 or opensearch, default es7
+	Kind     string
+	Hosts    []string
+	Username string
+	Password string
+	// Trace log the requests
+	Trace bool
+}
+
+// New return the engine of conf.Kind, the cluster must be reachable.
+func New(ctx context.Context, conf Config, log logr.Logger) (Engine, error) {
+	if len(conf.Hosts) == 0 {
+		return nil, errors.New("search engine host is must")
+	}
+
+	switch conf.Kind {
+	case "", KindES7:
+		return NewES7(ctx, conf, log)
+	case KindES8:
+		return NewES8(ctx, conf, log)
+	case KindOpenSearch:
+		return NewOpenSearch(ctx, conf, log)
+	}
+	return nil, fmt.Errorf("unknown search engine %q", conf.Kind)
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/go-logr/logr"
+	"github.com/olivere/elastic/v7"
+)
+
+type es7 struct {
+	client *elastic.Client
+}
+
+// NewES7 return the engine of elasticsearch 7 by olivere/elastic.
+func NewES7(ctx context.Context, conf Config, log logr.Logger) (Engine, error) {
+	opts := []elastic.ClientOptionFunc{
+		elastic.SetURL(conf.Hosts...),
+		elastic.SetErrorLog(errorLogger{log: log}),
+	}
+	if conf.Username != "" || conf.Password != "" {
+		opts = append(opts, elastic.SetBasicAuth(conf.Username, conf.Password))
+	}
+	if conf.Trace {
+		opts = append(opts, elastic.SetInfoLog(infoLogger{log: log}))
+	}
+
+	client, err := elastic.NewClient(opts...)
+	if err != nil {
+		return nil, es7Error(err)
+	}
+
+	_, _, err = client.Ping(conf.Hosts[0]).Do(ctx)
+	if err != nil {
+		client.Stop()
+		return nil, es7Error(err)
+	}
+	return &es7{
+		client: client,
+	}, nil
+}
+
+func (e *es7) Search(ctx context.Context, req *SearchRequest) (*SearchResult, error) {
+	search := e.client.Search(req.Indices...).Source(req.Body)
+	if req.Routing != "" {
+		search = search.Routing(req.Routing)
+	}
+	result, err := search.Do(ctx)
+	if err != nil {
+		return nil, es7Error(err)
+	}
+
+	r := &SearchResult{
+		TimedOut:        result.TimedOut,
+		TerminatedEarly: result.TerminatedEarly,
+		Hits:            make([]json.RawMessage, 0),
+	}
+	if result.Shards != nil {
+		r.FailedShards = result.Shards.Failed
+	}
+	if result.Hits != nil {
+		if result.Hits.TotalHits != nil {
+			r.Total = result.Hits.TotalHits.Value
+		}
+		for _, hit := range result.Hits.Hits {
+			r.Hits = append(r.Hits, hit.Source)
+		}
+	}
+	return r, nil
+}
+
+func (e *es7) Health(ctx context.Context) (string, error) {
+	health, err := e.client.ClusterHealth().Do(ctx)
+	if err != nil {
+		return "", es7Error(err)
+	}
+	return health.Status, nil
+}
+
+func (e *es7) IndexExists(ctx context.Context, index string) (bool, error) {
+	exists, err := e.client.IndexExists(index).Do(ctx)
+	return exists, es7Error(err)
+}
+
+func (e *es7) Mappings(ctx context.Context, index string) (map[string]map[string]interface{}, error) {
+	result, err := e.client.GetMapping().Index(index).Do(ctx)
+	if err != nil {
+		return nil, es7Error(err)
+	}
+
+	mappings := make(map[string]map[string]interface{}, len(result))
+	for name, mapping := range result {
+		m, _ := mapping.(map[string]interface{})
+		mappings[name], _ = m["mappings"].(map[string]interface{})
+	}
+	return mappings, nil
+}
+
+func (e *es7) Stop() {
+	e.client.Stop()
+}
+
+// es7Error convert the errors of olivere/elastic to the errors of the engines.
+func es7Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if elastic.IsConnErr(err) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	// the node is not reachable and marked as dead
+	var netErr net.Error
+	if errors.As(err, &netErr) && !netErr.Timeout() {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	var esErr *elastic.Error
+	if errors.As(err, &esErr) {
+		e := &Error{Status: esErr.Status}
+		if esErr.Details != nil {
+			e.Type, e.Reason = esErr.Details.Type, esErr.Details.Reason
+		}
+		return e
+	}
+	return err
+}
+
+type infoLogger struct {
+	log logr.Logger
+}
+
+func (l infoLogger) Printf(format string, v ...interface{}) {
+	l.log.Info(fmt.Sprintf(format, v...))
+}
+
+type errorLogger struct {
+	log logr.Logger
+}
+
+func (l errorLogger) Printf(format string, v ...interface{}) {
+	l.log.Error(fmt.Errorf(format, v...), "elasticsearch")
+}
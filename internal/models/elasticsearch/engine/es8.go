@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// mediaTypeES8 pin the REST API of elasticsearch to version 8.
+const mediaTypeES8 = "application/vnd.elasticsearch+json; compatible-with=8"
+
+// NewES8 return the engine of elasticsearch 8, the cluster must be
+// elasticsearch 8 or later, which is verified by the product header.
+func NewES8(ctx context.Context, conf Config, log logr.Logger) (Engine, error) {
+	e := newHTTPEngine(conf, log, http.Header{
+		"Accept":       []string{mediaTypeES8},
+		"Content-Type": []string{mediaTypeES8},
+	})
+
+	info, header, err := e.info(ctx)
+	if err != nil {
+		e.Stop()
+		return nil, err
+	}
+	if product := header.Get("X-Elastic-Product"); product != "Elasticsearch" {
+		e.Stop()
+		return nil, fmt.Errorf("the cluster is not elasticsearch, product %q", product)
+	}
+	if major, _ := strconv.Atoi(strings.SplitN(info.Version.Number, ".", 2)[0]); major < 8 {
+		e.Stop()
+		return nil, fmt.Errorf("elasticsearch %s is not supported by the es8 engine", info.Version.Number)
+	}
+	return e, nil
+}
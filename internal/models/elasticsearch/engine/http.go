@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// httpEngine engine speaking the REST API shared by elasticsearch 8 and opensearch,
+// the requests are balanced over the hosts and retried on the next host
+// if the host is not reachable.
+type httpEngine struct {
+	log      logr.Logger
+	client   *http.Client
+	hosts    []string
+	next     uint32
+	username string
+	password string
+	trace    bool
+	// header set on all requests
+	header http.Header
+}
+
+func newHTTPEngine(conf Config, log logr.Logger, header http.Header) *httpEngine {
+	hosts := make([]string, 0, len(conf.Hosts))
+	for _, host := range conf.Hosts {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		hosts = append(hosts, strings.TrimSuffix(host, "/"))
+	}
+	return &httpEngine{
+		log:      log,
+		client:   &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		hosts:    hosts,
+		username: conf.Username,
+		password: conf.Password,
+		trace:    conf.Trace,
+		header:   header,
+	}
+}
+
+// info body of GET /
+type info struct {
+	Version struct {
+		Number       string `json:"number"`
+		Distribution string `json:"distribution"`
+	} `json:"version"`
+}
+
+// info return the info of the cluster and the response header.
+func (e *httpEngine) info(ctx context.Context) (*info, http.Header, error) {
+	i := &info{}
+	header, err := e.do(ctx, http.MethodGet, "/", nil, nil, i)
+	return i, header, err
+}
+
+// searchResponse body of the search, hits.total is an object since elasticsearch 7,
+// or a number if rest_total_hits_as_int is set.
+type searchResponse struct {
+	TimedOut        bool `json:"timed_out"`
+	TerminatedEarly bool `json:"terminated_early"`
+	Shards          struct {
+		Failed int `json:"failed"`
+	} `json:"_shards"`
+	Hits struct {
+		Total json.RawMessage `json:"total"`
+		Hits  []struct {
+			Source json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (e *httpEngine) Search(ctx context.Context, req *SearchRequest) (*SearchResult, error) {
+	query := url.Values{}
+	if req.Routing != "" {
+		query.Set("routing", req.Routing)
+	}
+	path := "/_search"
+	if len(req.Indices) > 0 {
+		indices := make([]string, 0, len(req.Indices))
+		for _, index := range req.Indices {
+			indices = append(indices, url.PathEscape(index))
+		}
+		path = "/" + strings.Join(indices, ",") + path
+	}
+
+	resp := searchResponse{}
+	if _, err := e.do(ctx, http.MethodPost, path, query, req.Body, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{
+		TimedOut:        resp.TimedOut,
+		TerminatedEarly: resp.TerminatedEarly,
+		FailedShards:    resp.Shards.Failed,
+		Hits:            make([]json.RawMessage, 0, len(resp.Hits.Hits)),
+	}
+	total, err := parseTotal(resp.Hits.Total)
+	if err != nil {
+		return nil, err
+	}
+	result.Total = total
+	for _, hit := range resp.Hits.Hits {
+		result.Hits = append(result.Hits, hit.Source)
+	}
+	return result, nil
+}
+
+func parseTotal(raw json.RawMessage) (int64, error) {
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	if raw[0] == '{' {
+		total := struct {
+			Value int64 `json:"value"`
+		}{}
+		err := json.Unmarshal(raw, &total)
+		return total.Value, err
+	}
+	var total int64
+	err := json.Unmarshal(raw, &total)
+	return total, err
+}
+
+func (e *httpEngine) Health(ctx context.Context) (string, error) {
+	health := struct {
+		Status string `json:"status"`
+	}{}
+	_, err := e.do(ctx, http.MethodGet, "/_cluster/health", nil, nil, &health)
+	return health.Status, err
+}
+
+func (e *httpEngine) IndexExists(ctx context.Context, index string) (bool, error) {
+	_, err := e.do(ctx, http.MethodHead, "/"+url.PathEscape(index), nil, nil, nil)
+	if err == nil {
+		return true, nil
+	}
+	if e, ok := err.(*Error); ok && e.Status == http.StatusNotFound {
+		return false, nil
+	}
+	return false, err
+}
+
+func (e *httpEngine) Mappings(ctx context.Context, index string) (map[string]map[string]interface{}, error) {
+	result := map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}{}
+	if _, err := e.do(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_mapping", nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	mappings := make(map[string]map[string]interface{}, len(result))
+	for name, mapping := range result {
+		mappings[name] = mapping.Mappings
+	}
+	return mappings, nil
+}
+
+func (e *httpEngine) Stop() {
+	e.client.CloseIdleConnections()
+}
+
+// do send the request to the hosts in turn until one is reachable,
+// the json body of the response is decoded into out if not nil.
+// Only the transport errors are retried on the next host, the error responses
+// and the malformed bodies are returned as is.
+func (e *httpEngine) do(ctx context.Context, method, path string, query url.Values,
+	body, out interface{}) (http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	first := atomic.AddUint32(&e.next, 1)
+	for i := range e.hosts {
+		host := e.hosts[(int(first)+i)%len(e.hosts)]
+		header, err := e.send(ctx, host, method, path, query, payload, out)
+		if err == nil {
+			return header, nil
+		}
+		if !errors.Is(err, ErrUnavailable) || ctx.Err() != nil {
+			return header, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (e *httpEngine) send(ctx context.Context, host, method, path string, query url.Values,
+	payload []byte, out interface{}) (http.Header, error) {
+	u := host + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range e.header {
+		req.Header[key] = values
+	}
+	if e.username != "" || e.password != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	start := time.Now()
+	resp, err := e.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if e.trace {
+		e.log.Info("request", "method", method, "url", u, "status", resp.StatusCode,
+			"elapsed", time.Since(start).String())
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.Header, responseError(resp)
+	}
+	if out == nil || method == http.MethodHead {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return resp.Header, nil
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError decode the error of the response, the error is an object
+// with type and reason, or a string by some proxies.
+func responseError(resp *http.Response) error {
+	e := &Error{Status: resp.StatusCode, Reason: http.StatusText(resp.StatusCode)}
+	body := struct {
+		Error json.RawMessage `json:"error"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || len(body.Error) == 0 {
+		return e
+	}
+
+	detail := struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}{}
+	if err := json.Unmarshal(body.Error, &detail); err == nil {
+		e.Type, e.Reason = detail.Type, detail.Reason
+		return e
+	}
+	_ = json.Unmarshal(body.Error, &e.Reason)
+	return e
+}
@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-logr/logr"
+)
+
+// NewOpenSearch return the engine of opensearch, the cluster must report
+// the opensearch distribution.
+func NewOpenSearch(ctx context.Context, conf Config, log logr.Logger) (Engine, error) {
+	e := newHTTPEngine(conf, log, http.Header{
+		"Accept":       []string{"application/json"},
+		"Content-Type": []string{"application/json"},
+	})
+
+	info, _, err := e.info(ctx)
+	if err != nil {
+		e.Stop()
+		return nil, err
+	}
+	if info.Version.Distribution != "opensearch" {
+		e.Stop()
+		return nil, fmt.Errorf("the cluster is not opensearch, distribution %q", info.Version.Distribution)
+	}
+	return e, nil
+}
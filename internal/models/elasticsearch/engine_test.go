@@ -0,0 +1,54 @@
+package elasticsearch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+)
+
+// fakeEngine engine recording the searches and answering the result.
+type fakeEngine struct {
+	mu       sync.Mutex
+	requests []*engine.SearchRequest
+	result   *engine.SearchResult
+	// indices existing indices, keyed by name, valued by the mapping version
+	indices map[string]string
+}
+
+func (f *fakeEngine) Search(ctx context.Context, req *engine.SearchRequest) (*engine.SearchResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	if f.result == nil {
+		return &engine.SearchResult{}, nil
+	}
+	return f.result, nil
+}
+
+// lastRequest return the last search request.
+func (f *fakeEngine) lastRequest() *engine.SearchRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return nil
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+func (f *fakeEngine) Health(ctx context.Context) (string, error) {
+	return "green", nil
+}
+
+func (f *fakeEngine) IndexExists(ctx context.Context, index string) (bool, error) {
+	_, ok := f.indices[index]
+	return ok, nil
+}
+
+func (f *fakeEngine) Mappings(ctx context.Context, index string) (map[string]map[string]interface{}, error) {
+	return map[string]map[string]interface{}{
+		index: {"_meta": map[string]interface{}{"version": f.indices[index]}},
+	}, nil
+}
+
+func (f *fakeEngine) Stop() {}
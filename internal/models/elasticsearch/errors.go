@@ -0,0 +1,48 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+// wrapError classify the error returned by the engine.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return errdefs.From(err)
+	}
+	if errors.Is(err, engine.ErrUnavailable) {
+		return errdefs.Wrap(errdefs.Unavailable, err)
+	}
+
+	var engineErr *engine.Error
+	if errors.As(err, &engineErr) {
+		switch {
+		case engineErr.Status == 404:
+			return errdefs.Wrap(errdefs.NotFound, err)
+		case engineErr.Status == 408:
+			return errdefs.Wrap(errdefs.Timeout, err)
+		case engineErr.Status == 400:
+			return errdefs.Wrap(errdefs.InvalidArgument, err)
+		}
+		// unauthorized or forbidden by the engine is the fault of us, not the caller.
+		return errdefs.Wrap(errdefs.Unavailable, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return errdefs.Wrap(errdefs.Timeout, err)
+		}
+		return errdefs.Wrap(errdefs.Unavailable, err)
+	}
+
+	return errdefs.Wrap(errdefs.Internal, err)
+}
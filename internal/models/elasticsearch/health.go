@@ -0,0 +1,71 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/pkg/probe"
+)
+
+// HealthCheckers return the checkers of the cluster health, the indices existence
+// and the mapping version, the mapping version is checked if not empty.
+// current return the current engine and indices return its indices, which may be
+// swapped by reload, the checks pass if current returns nil, e.g. the storage is not elasticsearch.
+func HealthCheckers(current func() engine.Engine, indices func() []string, mappingVersion string) []probe.Checker {
+	return []probe.Checker{
+		probe.NewChecker("elasticsearch", func(ctx context.Context) error {
+			e := current()
+			if e == nil {
+				return nil
+			}
+			status, err := e.Health(ctx)
+			if err != nil {
+				return err
+			}
+			if status == "red" {
+				return errors.New("cluster is red")
+			}
+			return nil
+		}),
+		probe.NewChecker("indices", func(ctx context.Context) error {
+			e := current()
+			if e == nil {
+				return nil
+			}
+			for _, index := range indices() {
+				exists, err := e.IndexExists(ctx, index)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					return fmt.Errorf("index %s not exist", index)
+				}
+				if mappingVersion == "" {
+					continue
+				}
+				if err := checkMappingVersion(ctx, e, index, mappingVersion); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	}
+}
+
+// checkMappingVersion compare the _meta.version of the index mapping.
+func checkMappingVersion(ctx context.Context, e engine.Engine, index, version string) error {
+	result, err := e.Mappings(ctx, index)
+	if err != nil {
+		return err
+	}
+
+	for name, mappings := range result {
+		meta, _ := mappings["_meta"].(map[string]interface{})
+		if got := fmt.Sprint(meta["version"]); got != version {
+			return fmt.Errorf("mapping version of %s is %q, expect %q", name, got, version)
+		}
+	}
+	return nil
+}
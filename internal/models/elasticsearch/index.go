@@ -0,0 +1,98 @@
+package elasticsearch
+
+import (
+	"context"
+	"sort"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// IndexConfig index names of the repos
+type IndexConfig struct {
+	// Prefix prefix of all index names, e.g. staging-
+	Prefix string
+	// User name of the user index, default v1alpha1.UserIndex
+	User string
+	// Department name of the department index, default v1alpha1.DepartmentIndex
+	Department string
+	// Dedicated tenants with dedicated indices named by the index name and the tenant,
+	// e.g. user-tenant1, the other tenants share the index.
+	Dedicated []string
+	// Routing route the requests of the shared index by the tenant,
+	// the documents must be indexed with the same routing.
+	Routing bool
+}
+
+// Indices return the names of all indices.
+func (c IndexConfig) Indices() []string {
+	return append(newIndexer(c, c.User, v1alpha1.UserIndex).all(),
+		newIndexer(c, c.Department, v1alpha1.DepartmentIndex).all()...)
+}
+
+// indexer route the requests of the tenants to the indices.
+type indexer struct {
+	shared    string
+	dedicated map[string]string
+	routing   bool
+}
+
+func newIndexer(conf IndexConfig, name, defaultName string) indexer {
+	if name == "" {
+		name = defaultName
+	}
+	i := indexer{
+		shared:    conf.Prefix + name,
+		dedicated: make(map[string]string, len(conf.Dedicated)),
+		routing:   conf.Routing,
+	}
+	for _, tenantID := range conf.Dedicated {
+		i.dedicated[tenantID] = i.shared + "-" + tenantID
+	}
+	return i
+}
+
+// request return the search of source with the indices and the routing of the tenant,
+// all indices are searched if tenant is empty.
+func (i indexer) request(tenantID string, source *elastic.SearchSource) (*engine.SearchRequest, error) {
+	body, err := source.Source()
+	if err != nil {
+		return nil, err
+	}
+	req := &engine.SearchRequest{
+		Body: body,
+	}
+	switch index, ok := i.dedicated[tenantID]; {
+	case tenantID == "":
+		req.Indices = i.all()
+	case ok:
+		req.Indices = []string{index}
+	default:
+		req.Indices = []string{i.shared}
+		if i.routing {
+			req.Routing = tenantID
+		}
+	}
+	return req, nil
+}
+
+// search run source on the indices of the tenant.
+func (i indexer) search(ctx context.Context, e engine.Engine, tenantID string,
+	source *elastic.SearchSource) (*engine.SearchResult, error) {
+	req, err := i.request(tenantID, source)
+	if err != nil {
+		return nil, err
+	}
+	return e.Search(ctx, req)
+}
+
+func (i indexer) all() []string {
+	indices := make([]string, 0, len(i.dedicated)+1)
+	indices = append(indices, i.shared)
+	for _, index := range i.dedicated {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices[1:])
+	return indices
+}
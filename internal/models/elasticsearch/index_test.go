@@ -0,0 +1,104 @@
+package elasticsearch
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+func TestIndexerRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     IndexConfig
+		tenantID string
+		indices  []string
+		routing  string
+	}{{
+		name:     "default index",
+		tenantID: "t1",
+		indices:  []string{"user"},
+	}, {
+		name:     "prefix and name",
+		conf:     IndexConfig{Prefix: "staging-", User: "people"},
+		tenantID: "t1",
+		indices:  []string{"staging-people"},
+	}, {
+		name:     "shared index with routing",
+		conf:     IndexConfig{Dedicated: []string{"t2"}, Routing: true},
+		tenantID: "t1",
+		indices:  []string{"user"},
+		routing:  "t1",
+	}, {
+		name:     "shared index without routing",
+		conf:     IndexConfig{Dedicated: []string{"t2"}},
+		tenantID: "t1",
+		indices:  []string{"user"},
+	}, {
+		name:     "dedicated index is never routed",
+		conf:     IndexConfig{Prefix: "staging-", Dedicated: []string{"t2"}, Routing: true},
+		tenantID: "t2",
+		indices:  []string{"staging-user-t2"},
+	}, {
+		name:    "all indices without tenant",
+		conf:    IndexConfig{Prefix: "staging-", Dedicated: []string{"t3", "t2"}, Routing: true},
+		indices: []string{"staging-user", "staging-user-t2", "staging-user-t3"},
+	}}
+	for _, tt := range tests {
+		i := newIndexer(tt.conf, tt.conf.User, v1alpha1.UserIndex)
+		req, err := i.request(tt.tenantID, elastic.NewSearchSource())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(req.Indices, tt.indices) || req.Routing != tt.routing {
+			t.Errorf("%s: indices %v, routing %q, expect %v, %q", tt.name, req.Indices, req.Routing, tt.indices, tt.routing)
+		}
+	}
+}
+
+func TestIndices(t *testing.T) {
+	conf := IndexConfig{Prefix: "p-", Department: "dep", Dedicated: []string{"t1"}}
+	expect := []string{"p-user", "p-user-t1", "p-dep", "p-dep-t1"}
+	if indices := conf.Indices(); !reflect.DeepEqual(indices, expect) {
+		t.Errorf("indices %v, expect %v", indices, expect)
+	}
+}
+
+func TestHealthCheckers(t *testing.T) {
+	e := &fakeEngine{indices: map[string]string{"user": "1", "department": "1"}}
+	conf := IndexConfig{}
+	indices := func() []string { return conf.Indices() }
+	checkers := HealthCheckers(func() engine.Engine { return e }, indices, "1")
+
+	check := func() error {
+		for _, checker := range checkers {
+			if err := checker.Check(context.Background()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := check(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the indices are read on each check, e.g. changed by reload
+	conf.Prefix = "staging-"
+	if err := check(); err == nil || !strings.Contains(err.Error(), "staging-user") {
+		t.Errorf("missing index: %v", err)
+	}
+
+	conf.Prefix = ""
+	e.indices["user"] = "0"
+	if err := check(); err == nil || !strings.Contains(err.Error(), "mapping version") {
+		t.Errorf("mapping version: %v", err)
+	}
+
+	if err := HealthCheckers(func() engine.Engine { return nil }, indices, "1")[1].Check(context.Background()); err != nil {
+		t.Errorf("without engine: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/olivere/elastic/v7"
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"github.com/quanxiang-cloud/search/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan start the span of the elasticsearch request.
+func startSpan(ctx context.Context, repo, method, index string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "elasticsearch."+repo+"."+method,
+		semconv.DBSystemElasticsearch,
+		semconv.DBNameKey.String(index),
+		semconv.DBOperationKey.String(method),
+	)
+}
+
+// observe observe the latency, the error and the result size of the search,
+// the DSL of the slow search is logged.
+func (o searchOptions) observe(ctx context.Context, log logr.Logger, repo, method string, start time.Time,
+	source *elastic.SearchSource, result *engine.SearchResult, err error) {
+	elapsed := time.Since(start)
+	if o.slowThreshold > 0 && elapsed > o.slowThreshold {
+		dsl, _ := source.Source()
+		body, _ := json.Marshal(dsl)
+		util.LoggerWithRequest(ctx, log).Info("slow search",
+			"method", method,
+			"dsl", string(body),
+			"elapsed", elapsed.String(),
+		)
+	}
+
+	size := 0
+	if result != nil {
+		size = len(result.Hits)
+	}
+	err = wrapError(err)
+	metrics.ObserveES(repo, method, start, size, err)
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Int("elasticsearch.hits", size))
+	tracing.Error(span, err)
+}
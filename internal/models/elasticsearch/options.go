@@ -0,0 +1,82 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+)
+
+// Option option of the repos.
+type Option func(*searchOptions)
+
+// WithTimeout set the timeout of the search executed by shards,
+// the remaining time of the context deadline is used if not set.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *searchOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithTerminateAfter set the max number of documents collected by each shard.
+func WithTerminateAfter(terminateAfter int) Option {
+	return func(o *searchOptions) {
+		o.terminateAfter = terminateAfter
+	}
+}
+
+// WithSlowThreshold log the searches slower than threshold with their DSL,
+// zero means never.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(o *searchOptions) {
+		o.slowThreshold = threshold
+	}
+}
+
+// WithIndex set the index names and the routing of the tenants.
+func WithIndex(conf IndexConfig) Option {
+	return func(o *searchOptions) {
+		o.index = conf
+	}
+}
+
+type searchOptions struct {
+	index          IndexConfig
+	timeout        time.Duration
+	terminateAfter int
+	slowThreshold  time.Duration
+}
+
+func newSearchOptions(opts ...Option) searchOptions {
+	o := searchOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func (o searchOptions) apply(ctx context.Context, source *elastic.SearchSource) *elastic.SearchSource {
+	timeout := o.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+	if timeout > 0 {
+		source = source.Timeout(fmt.Sprintf("%dms", timeout.Milliseconds()))
+	}
+	if o.terminateAfter > 0 {
+		source = source.TerminateAfter(o.terminateAfter)
+	}
+	return source
+}
+
+// checkPartial mark the results as partial if some shards failed or timed out.
+func checkPartial(ctx context.Context, result *engine.SearchResult) {
+	if result.TimedOut || result.TerminatedEarly || result.FailedShards > 0 {
+		models.MetaFromContext(ctx).SetPartial()
+	}
+}
@@ -0,0 +1,132 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// sourceOf return the DSL of source as a json object.
+func sourceOf(t *testing.T, body interface{}) map[string]interface{} {
+	t.Helper()
+	if source, ok := body.(*elastic.SearchSource); ok {
+		var err error
+		if body, err = source.Source(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsl := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &dsl); err != nil {
+		t.Fatal(err)
+	}
+	return dsl
+}
+
+func TestApply(t *testing.T) {
+	deadline, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		opts           []Option
+		timeout        bool
+		maxTimeout     time.Duration
+		terminateAfter float64
+	}{{
+		name: "no options",
+		ctx:  context.Background(),
+	}, {
+		name:           "timeout and terminate after",
+		ctx:            context.Background(),
+		opts:           []Option{WithTimeout(time.Second), WithTerminateAfter(1000)},
+		timeout:        true,
+		maxTimeout:     time.Second,
+		terminateAfter: 1000,
+	}, {
+		name:       "deadline shorter than the timeout",
+		ctx:        deadline,
+		opts:       []Option{WithTimeout(time.Second)},
+		timeout:    true,
+		maxTimeout: 200 * time.Millisecond,
+	}, {
+		name:       "deadline without timeout",
+		ctx:        deadline,
+		timeout:    true,
+		maxTimeout: 200 * time.Millisecond,
+	}}
+	for _, tt := range tests {
+		dsl := sourceOf(t, newSearchOptions(tt.opts...).apply(tt.ctx, elastic.NewSearchSource()))
+
+		timeout, ok := dsl["timeout"].(string)
+		if ok != tt.timeout {
+			t.Errorf("%s: timeout %v", tt.name, dsl["timeout"])
+		}
+		if ok {
+			d, err := time.ParseDuration(timeout)
+			if err != nil || d <= 0 || d > tt.maxTimeout {
+				t.Errorf("%s: timeout %s, expect at most %s", tt.name, timeout, tt.maxTimeout)
+			}
+		}
+		if n, _ := dsl["terminate_after"].(float64); n != tt.terminateAfter {
+			t.Errorf("%s: terminate_after %v, expect %v", tt.name, dsl["terminate_after"], tt.terminateAfter)
+		}
+	}
+}
+
+func TestCheckPartial(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  engine.SearchResult
+		partial bool
+	}{
+		{"complete", engine.SearchResult{Total: 1}, false},
+		{"timed out", engine.SearchResult{TimedOut: true}, true},
+		{"terminated early", engine.SearchResult{TerminatedEarly: true}, true},
+		{"failed shards", engine.SearchResult{FailedShards: 1}, true},
+	}
+	for _, tt := range tests {
+		ctx, meta := models.WithMeta(context.Background())
+		checkPartial(ctx, &tt.result)
+		if meta.Partial() != tt.partial {
+			t.Errorf("%s: partial %v, expect %v", tt.name, meta.Partial(), tt.partial)
+		}
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	e := &fakeEngine{result: &engine.SearchResult{
+		Total:        1,
+		Hits:         []json.RawMessage{json.RawMessage(`{"id":"u1","name":"Alice"}`)},
+		TimedOut:     true,
+		FailedShards: 2,
+	}}
+	repo := NewUser(context.Background(), e, WithTimeout(time.Second), WithTerminateAfter(500))
+
+	ctx, meta := models.WithMeta(context.Background())
+	users, total, err := repo.Search(ctx, &v1alpha1.SearchUser{TenantID: "t1"}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(users) != 1 || users[0].ID != "u1" {
+		t.Errorf("users %v, total %d", users, total)
+	}
+	if !meta.Partial() {
+		t.Error("timed out result is not partial")
+	}
+
+	dsl := sourceOf(t, e.lastRequest().Body)
+	if dsl["timeout"] != "1000ms" || dsl["terminate_after"] != float64(500) {
+		t.Errorf("timeout %v, terminate_after %v", dsl["timeout"], dsl["terminate_after"])
+	}
+}
@@ -4,48 +4,57 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/olivere/elastic/v7"
 	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
 	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
 	"github.com/quanxiang-cloud/search/pkg/util"
 )
 
 type user struct {
 	log    logr.Logger
-	client *elastic.Client
+	engine engine.Engine
+
+	searchOptions
+	indexer
 }
 
 // NewUser new
-func NewUser(ctx context.Context, client *elastic.Client) models.UserRepo {
+func NewUser(ctx context.Context, e engine.Engine, opts ...Option) models.UserRepo {
+	o := newSearchOptions(opts...)
 	return &user{
-		log:    util.LoggerFromContext(ctx).WithName("user"),
-		client: client,
+		log:           util.LoggerFromContext(ctx).WithName("user"),
+		engine:        e,
+		searchOptions: o,
+		indexer:       newIndexer(o.index, o.index.User, v1alpha1.UserIndex),
 	}
 }
 
-func (u *user) index() string {
-	return "user"
-}
-
 func (u *user) Get(ctx context.Context, userID string) (*v1alpha1.User, error) {
-	result, err := u.client.Search().
-		Index(u.index()).
+	ctx, span := startSpan(ctx, "user", "get", u.shared)
+	defer span.End()
+
+	source := u.apply(ctx, elastic.NewSearchSource().
 		Query(
 			elastic.NewTermQuery("id", userID),
-		).
-		Do(ctx)
+		))
+	start := time.Now()
+	result, err := u.search(ctx, u.engine, models.TenantFromContext(ctx), source)
+	u.observe(ctx, u.log, "user", "get", start, source, result, err)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
+	checkPartial(ctx, result)
 
-	if len(result.Hits.Hits) == 0 {
+	if len(result.Hits) == 0 {
 		return nil, nil
 	}
 
 	user := new(v1alpha1.User)
-	err = json.Unmarshal(result.Hits.Hits[0].Source, user)
+	err = json.Unmarshal(result.Hits[0], user)
 	if err != nil {
 		return nil, err
 	}
@@ -54,26 +63,31 @@ func (u *user) Get(ctx context.Context, userID string) (*v1alpha1.User, error) {
 }
 
 func (u *user) List(ctx context.Context, userIDs []interface{}) ([]*v1alpha1.User, error) {
+	ctx, span := startSpan(ctx, "user", "list", u.shared)
+	defer span.End()
+
 	var size = 0
 	if len(userIDs) > 100 {
 		size = 99
 	} else {
 		size = len(userIDs)
 	}
-	result, err := u.client.Search().
-		Index(u.index()).
+	source := u.apply(ctx, elastic.NewSearchSource().
 		Query(
 			elastic.NewTermsQuery("id.keyword", userIDs...),
-		).From(0).Size(size).
-		Do(ctx)
+		).From(0).Size(size))
+	start := time.Now()
+	result, err := u.search(ctx, u.engine, models.TenantFromContext(ctx), source)
+	u.observe(ctx, u.log, "user", "list", start, source, result, err)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err)
 	}
+	checkPartial(ctx, result)
 
 	users := make([]*v1alpha1.User, 0, len(userIDs))
-	for _, hit := range result.Hits.Hits {
+	for _, hit := range result.Hits {
 		user := new(v1alpha1.User)
-		err := json.Unmarshal(hit.Source, user)
+		err := json.Unmarshal(hit, user)
 		if err != nil {
 			return nil, err
 		}
@@ -84,7 +98,10 @@ func (u *user) List(ctx context.Context, userIDs []interface{}) ([]*v1alpha1.Use
 }
 
 func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, size int) ([]*v1alpha1.User, int64, error) {
-	ql := u.client.Search().Index(u.index())
+	ctx, span := startSpan(ctx, "user", "search", u.shared)
+	defer span.End()
+
+	source := u.apply(ctx, elastic.NewSearchSource())
 
 	mustQuery := make([]elastic.Query, 0)
 
@@ -133,33 +150,36 @@ func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, siz
 	} else {
 		mustQuery = append(mustQuery, elastic.NewExistsQuery("tenantID"))
 	}
-	ql = ql.Query(elastic.NewBoolQuery().Must(mustQuery...))
+	source = source.Query(elastic.NewBoolQuery().Must(mustQuery...))
 	for _, orderBy := range query.OrderBy {
 		if strings.HasPrefix(orderBy, "-") {
-			ql = ql.Sort(orderBy[1:], true)
+			source = source.Sort(orderBy[1:], true)
 			continue
 		}
-		ql = ql.Sort(orderBy, false)
+		source = source.Sort(orderBy, false)
 	}
-	ql = ql.Sort("name.keyword", true)
+	source = source.Sort("name.keyword", true)
 
-	result, err := ql.From((page - 1) * size).Size(size).
-		Do(ctx)
+	source = source.From((page - 1) * size).Size(size)
+	start := time.Now()
+	result, err := u.search(ctx, u.engine, query.TenantID, source)
+	u.observe(ctx, u.log, "user", "search", start, source, result, err)
 
 	if err != nil {
-		u.log.Error(err, "user search")
-		return nil, 0, err
+		util.LoggerWithRequest(ctx, u.log).Error(err, "user search")
+		return nil, 0, wrapError(err)
 	}
+	checkPartial(ctx, result)
 
 	users := make([]*v1alpha1.User, 0, size)
-	for _, hit := range result.Hits.Hits {
+	for _, hit := range result.Hits {
 		user := new(v1alpha1.User)
-		err := json.Unmarshal(hit.Source, user)
+		err := json.Unmarshal(hit, user)
 		if err != nil {
 			return nil, 0, err
 		}
 		users = append(users, user)
 	}
 
-	return users, result.Hits.TotalHits.Value, nil
+	return users, result.Total, nil
 }
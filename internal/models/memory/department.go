@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+type department struct {
+	store *Store
+}
+
+// NewDepartment return the department repo of the store.
+func NewDepartment(store *Store) models.DepartmentRepo {
+	return &department{
+		store: store,
+	}
+}
+
+func (d *department) Search(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) ([]*v1alpha1.Department, int64, error) {
+	d.store.mu.RLock()
+	defer d.store.mu.RUnlock()
+
+	matched := make([]*v1alpha1.Department, 0)
+	for _, dep := range d.store.departments {
+		if matchDepartment(dep, query) {
+			matched = append(matched, dep)
+		}
+	}
+
+	indices := order(len(matched), func(i int) interface{} {
+		return matched[i]
+	}, sortFields(query.OrderBy, "id.keyword"))
+	from, to := paginate(len(indices), page, size)
+	deps := make([]*v1alpha1.Department, 0, to-from)
+	for _, i := range indices[from:to] {
+		deps = append(deps, matched[i])
+	}
+	return deps, int64(len(matched)), nil
+}
+
+func (d *department) List(ctx context.Context, depIDs []interface{}) ([]*v1alpha1.Department, error) {
+	d.store.mu.RLock()
+	defer d.store.mu.RUnlock()
+
+	ids := make(map[interface{}]struct{}, len(depIDs))
+	for _, id := range depIDs {
+		ids[id] = struct{}{}
+	}
+	deps := make([]*v1alpha1.Department, 0, len(depIDs))
+	for _, dep := range d.store.departments {
+		if _, ok := ids[dep.ID]; ok {
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// matchDepartment match the department like the query of the elasticsearch repo.
+func matchDepartment(dep *v1alpha1.Department, query *v1alpha1.SearchDepartment) bool {
+	if query.TenantID != "" && dep.TenantID != query.TenantID {
+		return false
+	}
+	if query.Name != "" && !matchPhrasePrefix(dep.Name, query.Name) {
+		return false
+	}
+	for _, attr := range query.Attr {
+		if dep.Attr != strconv.Itoa(attr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// tokenize split text into lowercase tokens like the standard analyzer of elasticsearch,
+// letters and digits form words, each ideograph is a token.
+func tokenize(text string) []string {
+	tokens := make([]string, 0)
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// matchPhrasePrefix like the match_phrase_prefix query, the tokens of query
+// appear in order in text, the last token is matched as prefix.
+func matchPhrasePrefix(text, query string) bool {
+	queryTokens := tokenize(query)
+	if len(queryTokens) == 0 {
+		return true
+	}
+	tokens := tokenize(text)
+	last := len(queryTokens) - 1
+	for i := 0; i+last < len(tokens); i++ {
+		matched := true
+		for j, token := range queryTokens {
+			if j == last {
+				matched = strings.HasPrefix(tokens[i+j], token)
+			} else if tokens[i+j] != token {
+				matched = false
+			}
+			if !matched {
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+type sortField struct {
+	field string
+	desc  bool
+}
+
+// sortFields return the fields of orderBy, prefixed by - for descending,
+// followed by the tie breaker.
+func sortFields(orderBy []string, tieBreaker string) []sortField {
+	fields := make([]sortField, 0, len(orderBy)+1)
+	for _, field := range orderBy {
+		if strings.HasPrefix(field, "-") {
+			fields = append(fields, sortField{field: field[1:], desc: true})
+			continue
+		}
+		fields = append(fields, sortField{field: field})
+	}
+	return append(fields, sortField{field: tieBreaker})
+}
+
+// order return the indices of n documents sorted by the json fields,
+// the documents missing the field are last.
+func order(n int, doc func(i int) interface{}, fields []sortField) []int {
+	indices := make([]int, n)
+	values := make([]map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		indices[i] = i
+		values[i] = toMap(doc(i))
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		a, b := values[indices[i]], values[indices[j]]
+		for _, f := range fields {
+			field := strings.TrimSuffix(f.field, ".keyword")
+			if c := compare(a[field], b[field], f.desc); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return indices
+}
+
+func compare(a, b interface{}, desc bool) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		default:
+			return -1
+		}
+	}
+
+	c := 0
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				c = -1
+			case a > b:
+				c = 1
+			}
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			c = strings.Compare(a, b)
+		}
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func toMap(doc interface{}) map[string]interface{} {
+	body, _ := json.Marshal(doc)
+	m := make(map[string]interface{})
+	_ = json.Unmarshal(body, &m)
+	return m
+}
+
+// paginate return the range of page in n documents.
+func paginate(n, page, size int) (int, int) {
+	from := (page - 1) * size
+	if from > n {
+		from = n
+	}
+	to := from + size
+	if to > n {
+		to = n
+	}
+	return from, to
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// Config memory backend config
+type Config struct {
+	// Fixtures path of the json file formed by {"users": [], "departments": []},
+	// the store is empty if not set.
+	Fixtures string
+}
+
+// Fixtures documents of the store.
+type Fixtures struct {
+	Users       []*v1alpha1.User       `json:"users"`
+	Departments []*v1alpha1.Department `json:"departments"`
+}
+
+// Store documents of the in-memory repos, safe for concurrent use.
+// Documents are kept in the order of put, keyed by id.
+type Store struct {
+	mu          sync.RWMutex
+	users       []*v1alpha1.User
+	departments []*v1alpha1.Department
+}
+
+// NewStore return the store with the fixtures.
+func NewStore(fixtures Fixtures) *Store {
+	s := &Store{}
+	s.PutUsers(fixtures.Users...)
+	s.PutDepartments(fixtures.Departments...)
+	return s
+}
+
+// Load return the store with the fixtures of the config.
+func Load(conf Config) (*Store, error) {
+	fixtures := Fixtures{}
+	if conf.Fixtures != "" {
+		body, err := ioutil.ReadFile(conf.Fixtures)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(body, &fixtures); err != nil {
+			return nil, err
+		}
+	}
+	return NewStore(fixtures), nil
+}
+
+// PutUsers create or replace the users.
+func (s *Store) PutUsers(users ...*v1alpha1.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range users {
+		replaced := false
+		for i := range s.users {
+			if s.users[i].ID == user.ID {
+				s.users[i], replaced = user, true
+				break
+			}
+		}
+		if !replaced {
+			s.users = append(s.users, user)
+		}
+	}
+}
+
+// PutDepartments create or replace the departments.
+func (s *Store) PutDepartments(deps ...*v1alpha1.Department) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, dep := range deps {
+		replaced := false
+		for i := range s.departments {
+			if s.departments[i].ID == dep.ID {
+				s.departments[i], replaced = dep, true
+				break
+			}
+		}
+		if !replaced {
+			s.departments = append(s.departments, dep)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+type user struct {
+	store *Store
+}
+
+// NewUser return the user repo of the store.
+func NewUser(store *Store) models.UserRepo {
+	return &user{
+		store: store,
+	}
+}
+
+func (u *user) Get(ctx context.Context, userID string) (*v1alpha1.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+
+	for _, user := range u.store.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (u *user) List(ctx context.Context, userIDs []interface{}) ([]*v1alpha1.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+
+	ids := make(map[interface{}]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		ids[id] = struct{}{}
+	}
+	users := make([]*v1alpha1.User, 0, len(userIDs))
+	for _, user := range u.store.users {
+		if _, ok := ids[user.ID]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, size int) ([]*v1alpha1.User, int64, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+
+	matched := make([]*v1alpha1.User, 0)
+	for _, user := range u.store.users {
+		if matchUser(user, query) {
+			matched = append(matched, user)
+		}
+	}
+
+	indices := order(len(matched), func(i int) interface{} {
+		return matched[i]
+	}, sortFields(query.OrderBy, "name.keyword"))
+	from, to := paginate(len(indices), page, size)
+	users := make([]*v1alpha1.User, 0, to-from)
+	for _, i := range indices[from:to] {
+		users = append(users, matched[i])
+	}
+	return users, int64(len(matched)), nil
+}
+
+// matchUser match the user like the query of the elasticsearch repo.
+func matchUser(user *v1alpha1.User, query *v1alpha1.SearchUser) bool {
+	if query.TenantID != "" && user.TenantID != query.TenantID {
+		return false
+	}
+	if query.DepartmentID != "" && !anyDepartment(user, func(dep v1alpha1.Department) bool {
+		return dep.ID == query.DepartmentID
+	}) {
+		return false
+	}
+	if query.DepartmentName != "" && !anyDepartment(user, func(dep v1alpha1.Department) bool {
+		return matchPhrasePrefix(dep.Name, query.DepartmentName)
+	}) {
+		return false
+	}
+	if query.RoleID != "" && !anyRole(user, func(role v1alpha1.Role) bool {
+		return role.ID == query.RoleID
+	}) {
+		return false
+	}
+	if query.RoleName != "" && !anyRole(user, func(role v1alpha1.Role) bool {
+		return matchPhrasePrefix(role.Name, query.RoleName)
+	}) {
+		return false
+	}
+	if query.LeaderID != "" && !anyLeader(user, query.LeaderID) {
+		return false
+	}
+	if query.UseStatus != 0 && user.UseStatus != query.UseStatus {
+		return false
+	}
+
+	prefixes := []struct {
+		text, query string
+	}{
+		{user.Name, query.Name},
+		{user.Phone, query.Phone},
+		{user.Email, query.Email},
+		{user.JobNumber, query.JobNumber},
+		{strconv.Itoa(user.Gender), query.Gender},
+		{user.Position, query.Position},
+	}
+	for _, prefix := range prefixes {
+		if prefix.query != "" && !matchPhrasePrefix(prefix.text, prefix.query) {
+			return false
+		}
+	}
+	return true
+}
+
+func anyDepartment(user *v1alpha1.User, match func(v1alpha1.Department) bool) bool {
+	for _, deps := range user.Departments {
+		for _, dep := range deps {
+			if match(dep) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyRole(user *v1alpha1.User, match func(v1alpha1.Role) bool) bool {
+	for _, role := range user.Roles {
+		if match(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyLeader(user *v1alpha1.User, leaderID string) bool {
+	for _, leaders := range user.Leaders {
+		for _, leader := range leaders {
+			if leader.ID == leaderID {
+				return true
+			}
+		}
+	}
+	return false
+}
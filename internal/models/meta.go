@@ -0,0 +1,51 @@
+package models
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type metaKey struct{}
+
+// Meta metadata of the results, collected by the repos during a request.
+type Meta struct {
+	partial int32
+	stale   int32
+}
+
+// WithMeta return a context carrying a new *Meta.
+func WithMeta(ctx context.Context) (context.Context, *Meta) {
+	meta := &Meta{}
+	return context.WithValue(ctx, metaKey{}, meta), meta
+}
+
+// MetaFromContext return the *Meta of the context,
+// a discarded *Meta is returned if not exist.
+func MetaFromContext(ctx context.Context) *Meta {
+	if meta, ok := ctx.Value(metaKey{}).(*Meta); ok {
+		return meta
+	}
+	return &Meta{}
+}
+
+// SetPartial mark the results as partial,
+// e.g. some shards timed out.
+func (m *Meta) SetPartial() {
+	atomic.StoreInt32(&m.partial, 1)
+}
+
+// Partial return true if the results are partial.
+func (m *Meta) Partial() bool {
+	return atomic.LoadInt32(&m.partial) == 1
+}
+
+// SetStale mark the results as stale,
+// e.g. served from the snapshot while the storage is down.
+func (m *Meta) SetStale() {
+	atomic.StoreInt32(&m.stale, 1)
+}
+
+// Stale return true if the results are stale.
+func (m *Meta) Stale() bool {
+	return atomic.LoadInt32(&m.stale) == 1
+}
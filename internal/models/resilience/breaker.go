@@ -0,0 +1,176 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/probe"
+)
+
+// State state of the circuit breaker
+type State int
+
+// states of the circuit breaker
+const (
+	// StateClosed the calls pass.
+	StateClosed State = iota
+	// StateHalfOpen the cooldown passed, one trial call passes.
+	StateHalfOpen
+	// StateOpen the calls fail fast.
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// ErrOpen returned by the calls rejected by the open breaker.
+var ErrOpen = errdefs.NewUnavailable("circuit breaker is open")
+
+// Breaker circuit breaker opened by the consecutive failures, the calls fail fast
+// with ErrOpen until the cooldown passes, then one trial call decides to close
+// the breaker or open it again. The nil breaker allows all calls.
+type Breaker struct {
+	log      logr.Logger
+	failures int
+	cooldown time.Duration
+
+	mu          sync.Mutex
+	state       State
+	consecutive int
+	openedAt    time.Time
+	// trial true if the trial call of half-open is in flight
+	trial bool
+	now   func() time.Time
+}
+
+// NewBreaker return the circuit breaker of the config.
+func NewBreaker(conf BreakerConfig, log logr.Logger) *Breaker {
+	b := &Breaker{
+		log:      log,
+		failures: conf.Failures,
+		cooldown: conf.Cooldown,
+		now:      time.Now,
+	}
+	if b.failures <= 0 {
+		b.failures = 1
+	}
+	if b.cooldown <= 0 {
+		b.cooldown = 10 * time.Second
+	}
+	metrics.SetBreakerState(int(StateClosed))
+	return b
+}
+
+// State return the current state.
+func (b *Breaker) State() State {
+	if b == nil {
+		return StateClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current()
+}
+
+// Allow return ErrOpen if the call is rejected,
+// otherwise the result of the call must be reported.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.current() {
+	case StateClosed:
+		return nil
+	case StateHalfOpen:
+		if b.trial {
+			return ErrOpen
+		}
+		b.trial = true
+		return nil
+	}
+	return ErrOpen
+}
+
+// success report the allowed call succeeded.
+func (b *Breaker) success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutive = 0
+	b.trial = false
+	if b.state != StateClosed {
+		b.set(StateClosed)
+	}
+}
+
+// failure report the allowed call failed.
+func (b *Breaker) failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutive++
+	if b.trial || (b.state == StateClosed && b.consecutive >= b.failures) {
+		b.trial = false
+		b.openedAt = b.now()
+		b.set(StateOpen)
+	}
+}
+
+// release report the allowed call told nothing, e.g. canceled by the caller.
+func (b *Breaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// current return the state, the open breaker turns half-open after the cooldown.
+func (b *Breaker) current() State {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.set(StateHalfOpen)
+	}
+	return b.state
+}
+
+func (b *Breaker) set(state State) {
+	if b.state == state {
+		return
+	}
+	b.log.Info("state changed", "from", b.state.String(), "to", state.String(),
+		"consecutiveFailures", b.consecutive)
+	b.state = state
+	metrics.SetBreakerState(int(state))
+}
+
+// HealthChecker return the checker failing while the breaker is open.
+// breaker return the current breaker, which may be swapped by reload,
+// the check passes if it returns nil, e.g. the breaker is disabled.
+func HealthChecker(breaker func() *Breaker) probe.Checker {
+	return probe.NewChecker("breaker", func(ctx context.Context) error {
+		b := breaker()
+		if state := b.State(); state == StateOpen {
+			return errdefs.NewUnavailable("circuit breaker is %s", state)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,104 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// newTestBreaker return the breaker with the clock moved by the returned func.
+func newTestBreaker(failures int, cooldown time.Duration) (*Breaker, func(d time.Duration)) {
+	b := NewBreaker(BreakerConfig{Failures: failures, Cooldown: cooldown}, logr.Discard())
+	now := time.Unix(0, 0)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBreaker(t *testing.T) {
+	b, advance := newTestBreaker(3, 10*time.Second)
+
+	// the failures must be consecutive
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatal(err)
+		}
+		b.failure()
+	}
+	_ = b.Allow()
+	b.success()
+	for i := 0; i < 2; i++ {
+		_ = b.Allow()
+		b.failure()
+	}
+	if state := b.State(); state != StateClosed {
+		t.Fatalf("after the failures reset: %s", state)
+	}
+
+	_ = b.Allow()
+	b.failure()
+	if state := b.State(); state != StateOpen {
+		t.Fatalf("after %d consecutive failures: %s", 3, state)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("open: %v", err)
+	}
+
+	advance(9 * time.Second)
+	if state := b.State(); state != StateOpen {
+		t.Errorf("before the cooldown: %s", state)
+	}
+	advance(time.Second)
+	if state := b.State(); state != StateHalfOpen {
+		t.Fatalf("after the cooldown: %s", state)
+	}
+
+	// one trial call passes, the failed trial opens the breaker again
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial: %v", err)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Errorf("during the trial: %v", err)
+	}
+	b.failure()
+	if state := b.State(); state != StateOpen {
+		t.Fatalf("after the failed trial: %s", state)
+	}
+
+	// the released trial lets another call try
+	advance(10 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial: %v", err)
+	}
+	b.release()
+	if state := b.State(); state != StateHalfOpen {
+		t.Fatalf("after the released trial: %s", state)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial after release: %v", err)
+	}
+	b.success()
+	if state := b.State(); state != StateClosed {
+		t.Fatalf("after the succeeded trial: %s", state)
+	}
+
+	// the failures are counted from the close
+	_ = b.Allow()
+	b.failure()
+	if state := b.State(); state != StateClosed {
+		t.Errorf("after one failure of the closed breaker: %s", state)
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	if err := b.Allow(); err != nil {
+		t.Error(err)
+	}
+	b.failure()
+	b.success()
+	b.release()
+	if state := b.State(); state != StateClosed {
+		t.Errorf("nil breaker: %s", state)
+	}
+}
@@ -0,0 +1,75 @@
+package resilience
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+type user struct {
+	repo models.UserRepo
+	r    *Resilience
+}
+
+// NewUser return the user repo guarding the calls of repo,
+// all methods of the repos are idempotent reads.
+func NewUser(repo models.UserRepo, r *Resilience) models.UserRepo {
+	return &user{
+		repo: repo,
+		r:    r,
+	}
+}
+
+func (u *user) Get(ctx context.Context, userID string) (user *v1alpha1.User, err error) {
+	err = u.r.do(ctx, KindUser, "get", func(ctx context.Context) error {
+		user, err = u.repo.Get(ctx, userID)
+		return err
+	})
+	return user, err
+}
+
+func (u *user) List(ctx context.Context, userIDs []interface{}) (users []*v1alpha1.User, err error) {
+	err = u.r.do(ctx, KindUser, "list", func(ctx context.Context) error {
+		users, err = u.repo.List(ctx, userIDs)
+		return err
+	})
+	return users, err
+}
+
+func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, size int) (users []*v1alpha1.User, total int64, err error) {
+	err = u.r.do(ctx, KindUser, "search", func(ctx context.Context) error {
+		users, total, err = u.repo.Search(ctx, query, page, size)
+		return err
+	})
+	return users, total, err
+}
+
+type department struct {
+	repo models.DepartmentRepo
+	r    *Resilience
+}
+
+// NewDepartment return the department repo guarding the calls of repo.
+func NewDepartment(repo models.DepartmentRepo, r *Resilience) models.DepartmentRepo {
+	return &department{
+		repo: repo,
+		r:    r,
+	}
+}
+
+func (d *department) Search(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) (deps []*v1alpha1.Department, total int64, err error) {
+	err = d.r.do(ctx, KindDepartment, "search", func(ctx context.Context) error {
+		deps, total, err = d.repo.Search(ctx, query, page, size)
+		return err
+	})
+	return deps, total, err
+}
+
+func (d *department) List(ctx context.Context, depIDs []interface{}) (deps []*v1alpha1.Department, err error) {
+	err = d.r.do(ctx, KindDepartment, "list", func(ctx context.Context) error {
+		deps, err = d.repo.List(ctx, depIDs)
+		return err
+	})
+	return deps, err
+}
@@ -0,0 +1,165 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// kinds of the guarded repos
+const (
+	KindUser       = "user"
+	KindDepartment = "department"
+)
+
+// Config resilience config of the repo calls
+type Config struct {
+	// Retries max retries of the failed reads, zero means never
+	Retries int
+	// Backoff base delay before the first retry, doubled on each retry
+	// and fully jittered, default 50ms
+	Backoff time.Duration
+	// MaxBackoff max delay before a retry, default 1s
+	MaxBackoff time.Duration
+	Breaker    BreakerConfig
+}
+
+// BreakerConfig circuit breaker config
+type BreakerConfig struct {
+	// Failures consecutive failures opening the breaker, zero means no breaker
+	Failures int
+	// Cooldown duration the breaker stays open before a trial call, default 10s
+	Cooldown time.Duration
+}
+
+// Resilience retry the failed reads of the repos with jittered backoff,
+// and fail fast by the circuit breaker while the storage is down.
+// Only the errors of unavailable or timeout are retried and counted by the breaker,
+// the others mean the storage did answer.
+type Resilience struct {
+	log        logr.Logger
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	// breaker nil if disabled
+	breaker *Breaker
+}
+
+// New return the resilience of the config, nil if both retries and breaker are disabled.
+func New(ctx context.Context, conf Config) *Resilience {
+	if conf.Retries <= 0 && conf.Breaker.Failures <= 0 {
+		return nil
+	}
+
+	log := util.LoggerFromContext(ctx).WithName("resilience")
+	r := &Resilience{
+		log:        log,
+		retries:    conf.Retries,
+		backoff:    conf.Backoff,
+		maxBackoff: conf.MaxBackoff,
+	}
+	if r.retries < 0 {
+		r.retries = 0
+	}
+	if r.backoff <= 0 {
+		r.backoff = 50 * time.Millisecond
+	}
+	if r.maxBackoff <= 0 {
+		r.maxBackoff = time.Second
+	}
+	if conf.Breaker.Failures > 0 {
+		r.breaker = NewBreaker(conf.Breaker, log.WithName("breaker"))
+	}
+	return r
+}
+
+// Breaker return the circuit breaker, nil if disabled.
+func (r *Resilience) Breaker() *Breaker {
+	if r == nil {
+		return nil
+	}
+	return r.breaker
+}
+
+// do call fn allowed by the breaker, the result of the call with its retries
+// is reported to the breaker once.
+func (r *Resilience) do(ctx context.Context, kind, method string, fn func(ctx context.Context) error) error {
+	if err := r.breaker.Allow(); err != nil {
+		metrics.ObserveBreakerRejection(kind, method)
+		return err
+	}
+	err := r.retry(ctx, kind, method, fn)
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		// the caller gave up, which tells nothing about the storage
+		r.breaker.release()
+	case failure(err):
+		r.breaker.failure()
+	default:
+		r.breaker.success()
+	}
+	return err
+}
+
+// retry call fn until it succeeds, fails with an error not retryable, runs out of retries,
+// ctx is done or the breaker is opened by the other calls, the last error is returned.
+func (r *Resilience) retry(ctx context.Context, kind, method string, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= r.retries || !retryable(err) || r.breaker.State() == StateOpen {
+			return err
+		}
+
+		delay := r.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// no time left for another try
+			return err
+		}
+		util.LoggerWithRequest(ctx, r.log).Info("retry", "kind", kind, "method", method,
+			"attempt", attempt+1, "delay", delay.String(), "error", err.Error())
+		metrics.ObserveRetry(kind, method)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// delay return the full jittered backoff before the retry of attempt.
+func (r *Resilience) delay(attempt int) time.Duration {
+	max := r.maxBackoff
+	if attempt < 30 {
+		if d := r.backoff << uint(attempt); d > 0 && d < max {
+			max = d
+		}
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// failure report whether err means the storage is unreachable or overloaded.
+func failure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch errdefs.From(err).Code {
+	case errdefs.Unavailable, errdefs.Timeout:
+		return true
+	}
+	return false
+}
+
+// retryable report whether the call failed with err may succeed if retried,
+// the timeout is not retried as the query may be too heavy to answer in time.
+func retryable(err error) bool {
+	return err != nil && errdefs.From(err).Code == errdefs.Unavailable
+}
@@ -0,0 +1,137 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+func newTestResilience(retries, failures int) (*Resilience, func(d time.Duration)) {
+	b, advance := newTestBreaker(failures, 10*time.Second)
+	return &Resilience{
+		log:        logr.Discard(),
+		retries:    retries,
+		backoff:    time.Microsecond,
+		maxBackoff: time.Microsecond,
+		breaker:    b,
+	}, advance
+}
+
+// calls return fn failing with the errs in order and succeeding after them,
+// and the number of the calls.
+func calls(errs ...error) (func(ctx context.Context) error, *int) {
+	n := 0
+	return func(ctx context.Context) error {
+		n++
+		if n <= len(errs) {
+			return errs[n-1]
+		}
+		return nil
+	}, &n
+}
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	unavailable := errdefs.NewUnavailable("unavailable")
+	timeout := errdefs.New(errdefs.Timeout, "timeout")
+	invalid := errdefs.NewInvalidArgument("invalid")
+
+	tests := []struct {
+		name  string
+		errs  []error
+		err   error
+		calls int
+	}{
+		{"success", nil, nil, 1},
+		{"retried", []error{unavailable, unavailable}, nil, 3},
+		{"out of retries", []error{unavailable, unavailable, unavailable}, unavailable, 3},
+		{"timeout is not retried", []error{timeout}, timeout, 1},
+		{"invalid is not retried", []error{invalid}, invalid, 1},
+	}
+	for _, tt := range tests {
+		r, _ := newTestResilience(2, 100)
+		fn, n := calls(tt.errs...)
+		err := r.do(ctx, KindUser, "get", fn)
+		if err != tt.err || *n != tt.calls {
+			t.Errorf("%s: error %v, calls %d, expect %v, %d", tt.name, err, *n, tt.err, tt.calls)
+		}
+	}
+}
+
+func TestDoBreaker(t *testing.T) {
+	ctx := context.Background()
+	unavailable := errdefs.NewUnavailable("unavailable")
+
+	// one failure per call, whatever the retries
+	r, advance := newTestResilience(2, 2)
+	fn, n := calls(unavailable, unavailable, unavailable)
+	if err := r.do(ctx, KindUser, "get", fn); err != unavailable || *n != 3 {
+		t.Fatalf("first call: %v, calls %d", err, *n)
+	}
+	if state := r.breaker.State(); state != StateClosed {
+		t.Fatalf("after one failed call: %s", state)
+	}
+	fn, _ = calls(unavailable, unavailable, unavailable)
+	_ = r.do(ctx, KindUser, "get", fn)
+	if state := r.breaker.State(); state != StateOpen {
+		t.Fatalf("after two failed calls: %s", state)
+	}
+
+	fn, n = calls()
+	if err := r.do(ctx, KindUser, "get", fn); err != ErrOpen || *n != 0 {
+		t.Errorf("open: %v, calls %d", err, *n)
+	}
+
+	// the failed trial returns its own error, not ErrOpen
+	advance(10 * time.Second)
+	fn, n = calls(unavailable, unavailable, unavailable)
+	if err := r.do(ctx, KindUser, "get", fn); err != unavailable || *n != 3 {
+		t.Errorf("failed trial: %v, calls %d", err, *n)
+	}
+	if state := r.breaker.State(); state != StateOpen {
+		t.Fatalf("after the failed trial: %s", state)
+	}
+
+	// the retried trial closes the breaker
+	advance(10 * time.Second)
+	fn, n = calls(unavailable)
+	if err := r.do(ctx, KindUser, "get", fn); err != nil || *n != 2 {
+		t.Errorf("retried trial: %v, calls %d", err, *n)
+	}
+	if state := r.breaker.State(); state != StateClosed {
+		t.Fatalf("after the retried trial: %s", state)
+	}
+
+	// the canceled call tells nothing
+	r, _ = newTestResilience(0, 1)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := r.do(canceled, KindUser, "get", func(ctx context.Context) error {
+		return errdefs.NewUnavailable("%s", ctx.Err())
+	}); err == nil {
+		t.Error("canceled: expect error")
+	}
+	if state := r.breaker.State(); state != StateClosed {
+		t.Errorf("after the canceled call: %s", state)
+	}
+}
+
+func TestRetryStopsIfOpen(t *testing.T) {
+	r, _ := newTestResilience(5, 1)
+	unavailable := errdefs.NewUnavailable("unavailable")
+	n := 0
+	err := r.do(context.Background(), KindUser, "get", func(ctx context.Context) error {
+		n++
+		// another call opens the breaker
+		_ = r.breaker.Allow()
+		r.breaker.failure()
+		return unavailable
+	})
+	if !errors.Is(err, unavailable) || n != 1 {
+		t.Errorf("error %v, calls %d", err, n)
+	}
+}
@@ -0,0 +1,175 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// drivers of the supported databases
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// drivers
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite3"
+)
+
+// Config sql backend config
+type Config struct {
+	// Driver postgres, mysql or sqlite3
+	Driver string
+	// DSN data source name of the driver, e.g. file::memory:?cache=shared for sqlite3
+	DSN string
+	// Migrate create the tables if not exist
+	Migrate bool
+	// MaxOpenConns zero means unlimited
+	MaxOpenConns int
+	// MaxIdleConns zero means the default of database/sql
+	MaxIdleConns int
+	// ConnMaxLifetime zero means the connections are reused forever
+	ConnMaxLifetime time.Duration
+}
+
+// DB database of the sql repos.
+type DB struct {
+	db     *sql.DB
+	driver string
+}
+
+// Open open the database of the config, the tables are created if conf.Migrate.
+func Open(ctx context.Context, conf Config) (*DB, error) {
+	switch conf.Driver {
+	case DriverPostgres, DriverMySQL, DriverSQLite:
+	default:
+		return nil, fmt.Errorf("unknown sql driver %q", conf.Driver)
+	}
+
+	db, err := sql.Open(conf.Driver, conf.DSN)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(conf.MaxOpenConns)
+	if conf.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(conf.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	d := &DB{
+		db:     db,
+		driver: conf.Driver,
+	}
+	if conf.Migrate {
+		if err := d.Migrate(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Close close the database.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Ping check the connection.
+func (d *DB) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+// rebind replace the ? placeholders by the placeholders of the driver.
+func (d *DB) rebind(query string) string {
+	if d.driver != DriverPostgres {
+		return query
+	}
+
+	b := strings.Builder{}
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (d *DB) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, d.rebind(query), args...)
+}
+
+func (d *DB) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(ctx, d.rebind(query), args...)
+}
+
+// maxParams max parameters of the IN lists, the default limit of sqlite before 3.32.
+const maxParams = 999
+
+// chunks split args into the chunks of at most maxParams args.
+func chunks(args []interface{}) [][]interface{} {
+	result := make([][]interface{}, 0, len(args)/maxParams+1)
+	for len(args) > maxParams {
+		result = append(result, args[:maxParams:maxParams])
+		args = args[maxParams:]
+	}
+	return append(result, args)
+}
+
+// placeholders return n placeholders separated by comma.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// escape escape the wildcards of LIKE by !.
+func escape(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// prefixLike approximate the match_phrase_prefix query of elasticsearch,
+// query is a prefix of the text or of any word of the text, case insensitive.
+func prefixLike(column, query string) (string, []interface{}) {
+	q := escape(strings.ToLower(query))
+	return "(LOWER(" + column + ") LIKE ? ESCAPE '!' OR LOWER(" + column + ") LIKE ? ESCAPE '!')",
+		[]interface{}{q + "%", "% " + q + "%"}
+}
+
+// orderBy return the ORDER BY clause of the fields prefixed by - for descending,
+// columns maps the json fields to the columns.
+func orderBy(fields []string, columns map[string]string, tieBreakers ...string) (string, error) {
+	clauses := make([]string, 0, len(fields)+len(tieBreakers))
+	for _, field := range fields {
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			field, direction = field[1:], "DESC"
+		}
+		column, ok := columns[strings.TrimSuffix(field, ".keyword")]
+		if !ok {
+			return "", fmt.Errorf("unknown order field %q", field)
+		}
+		clauses = append(clauses, column+" "+direction)
+	}
+	for _, column := range tieBreakers {
+		clauses = append(clauses, column+" ASC")
+	}
+	return " ORDER BY " + strings.Join(clauses, ", "), nil
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,115 @@
+package sqldb
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+const departmentColumns = "d.id, d.tenant_id, d.name, d.pid, d.attr"
+
+// departmentOrder columns of the orderBy fields.
+var departmentOrder = map[string]string{
+	"id":   "d.id",
+	"name": "d.name",
+	"pid":  "d.pid",
+	"attr": "d.attr",
+}
+
+type department struct {
+	db *DB
+}
+
+// NewDepartment return the department repo of the database.
+func NewDepartment(db *DB) models.DepartmentRepo {
+	return &department{
+		db: db,
+	}
+}
+
+func (d *department) Search(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) ([]*v1alpha1.Department, int64, error) {
+	ctx, span := d.db.startSpan(ctx, "department", "search")
+	defer span.End()
+
+	order, err := orderBy(query.OrderBy, departmentOrder, "d.id")
+	if err != nil {
+		return nil, 0, fail(span, errdefs.Wrap(errdefs.InvalidArgument, err))
+	}
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if query.TenantID != "" {
+		conditions = append(conditions, "d.tenant_id = ?")
+		args = append(args, query.TenantID)
+	}
+	if query.Name != "" {
+		like, values := prefixLike("d.name", query.Name)
+		conditions = append(conditions, like)
+		args = append(args, values...)
+	}
+	for _, attr := range query.Attr {
+		conditions = append(conditions, "d.attr = ?")
+		args = append(args, strconv.Itoa(attr))
+	}
+
+	var total int64
+	err = d.db.queryRow(ctx, "SELECT COUNT(*) FROM departments d"+where(conditions), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+
+	deps, err := d.find(ctx, where(conditions)+order+" LIMIT ? OFFSET ?",
+		append(args, size, offset(page, size))...)
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+	return deps, total, nil
+}
+
+func (d *department) List(ctx context.Context, depIDs []interface{}) ([]*v1alpha1.Department, error) {
+	ctx, span := d.db.startSpan(ctx, "department", "list")
+	defer span.End()
+
+	if len(depIDs) == 0 {
+		return []*v1alpha1.Department{}, nil
+	}
+	deps := make([]*v1alpha1.Department, 0, len(depIDs))
+	idChunks := chunks(depIDs)
+	for _, ids := range idChunks {
+		found, err := d.find(ctx, " WHERE d.id IN ("+placeholders(len(ids))+") ORDER BY d.id", ids...)
+		if err != nil {
+			return nil, fail(span, err)
+		}
+		deps = append(deps, found...)
+	}
+	if len(idChunks) > 1 {
+		// each chunk is ordered by id.
+		sort.Slice(deps, func(i, j int) bool {
+			return deps[i].ID < deps[j].ID
+		})
+	}
+	return deps, nil
+}
+
+// find return the departments selected by the clauses.
+func (d *department) find(ctx context.Context, clauses string, args ...interface{}) ([]*v1alpha1.Department, error) {
+	rows, err := d.db.query(ctx, "SELECT "+departmentColumns+" FROM departments d"+clauses, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deps := make([]*v1alpha1.Department, 0)
+	for rows.Next() {
+		dep := new(v1alpha1.Department)
+		if err := rows.Scan(&dep.ID, &dep.TenantID, &dep.Name, &dep.PID, &dep.Attr); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, rows.Err()
+}
@@ -0,0 +1,60 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// wrapError classify the error returned by the database.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return errdefs.From(err)
+	case errors.Is(err, driver.ErrBadConn):
+		return errdefs.Wrap(errdefs.Unavailable, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return errdefs.Wrap(errdefs.Timeout, err)
+		}
+		return errdefs.Wrap(errdefs.Unavailable, err)
+	}
+
+	return errdefs.Wrap(errdefs.Internal, err)
+}
+
+var systems = map[string]attribute.KeyValue{
+	DriverPostgres: semconv.DBSystemPostgreSQL,
+	DriverMySQL:    semconv.DBSystemMySQL,
+	DriverSQLite:   semconv.DBSystemSqlite,
+}
+
+// startSpan start the span of the query.
+func (d *DB) startSpan(ctx context.Context, repo, method string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "sql."+repo+"."+method,
+		systems[d.driver],
+		semconv.DBSQLTableKey.String(repo+"s"),
+		semconv.DBOperationKey.String(method),
+	)
+}
+
+// fail classify err and record it on the span.
+func fail(span trace.Span, err error) error {
+	err = wrapError(err)
+	tracing.Error(span, err)
+	return err
+}
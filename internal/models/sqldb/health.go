@@ -0,0 +1,20 @@
+package sqldb
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/search/pkg/probe"
+)
+
+// HealthChecker return the checker of the database connection.
+// db return the current database, which may be swapped by reload,
+// the check passes if it returns nil, e.g. the storage is not sql.
+func HealthChecker(db func() *DB) probe.Checker {
+	return probe.NewChecker("sql", func(ctx context.Context) error {
+		d := db()
+		if d == nil {
+			return nil
+		}
+		return d.Ping(ctx)
+	})
+}
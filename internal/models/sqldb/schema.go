@@ -0,0 +1,194 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// tables of the repos, the types are portable across the drivers.
+// The relations of the users are normalized, the names of the departments
+// and the leaders are joined from their own tables.
+var tables = []string{
+	`CREATE TABLE IF NOT EXISTS departments (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		name VARCHAR(255) NOT NULL DEFAULT '',
+		pid VARCHAR(64) NOT NULL DEFAULT '',
+		attr VARCHAR(32) NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		tenant_id VARCHAR(64) NOT NULL DEFAULT '',
+		name VARCHAR(255) NOT NULL DEFAULT '',
+		phone VARCHAR(64) NOT NULL DEFAULT '',
+		email VARCHAR(255) NOT NULL DEFAULT '',
+		created_at BIGINT NOT NULL DEFAULT 0,
+		job_number VARCHAR(64) NOT NULL DEFAULT '',
+		avatar VARCHAR(1024) NOT NULL DEFAULT '',
+		use_status INTEGER NOT NULL DEFAULT 0,
+		gender INTEGER NOT NULL DEFAULT 0,
+		source VARCHAR(64) NOT NULL DEFAULT '',
+		self_email VARCHAR(255) NOT NULL DEFAULT '',
+		position VARCHAR(255) NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS roles (
+		id VARCHAR(64) NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_departments (
+		user_id VARCHAR(64) NOT NULL,
+		path INTEGER NOT NULL,
+		level INTEGER NOT NULL,
+		department_id VARCHAR(64) NOT NULL,
+		PRIMARY KEY (user_id, path, level)
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_roles (
+		user_id VARCHAR(64) NOT NULL,
+		role_id VARCHAR(64) NOT NULL,
+		PRIMARY KEY (user_id, role_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_leaders (
+		user_id VARCHAR(64) NOT NULL,
+		path INTEGER NOT NULL,
+		level INTEGER NOT NULL,
+		leader_id VARCHAR(64) NOT NULL,
+		attr VARCHAR(32) NOT NULL DEFAULT '',
+		PRIMARY KEY (user_id, path, level)
+	)`,
+}
+
+// Migrate create the tables if not exist.
+func (d *DB) Migrate(ctx context.Context) error {
+	for _, table := range tables {
+		if _, err := d.db.ExecContext(ctx, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tx struct {
+	*sql.Tx
+	d *DB
+}
+
+func (t tx) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := t.ExecContext(ctx, t.d.rebind(query), args...)
+	return err
+}
+
+// exists return true if the row of id exists in table.
+func (t tx) exists(ctx context.Context, table, id string) (bool, error) {
+	var n int
+	err := t.QueryRowContext(ctx, t.d.rebind("SELECT COUNT(*) FROM "+table+" WHERE id = ?"), id).Scan(&n)
+	return n > 0, err
+}
+
+func (d *DB) inTx(ctx context.Context, fn func(tx) error) error {
+	t, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx{Tx: t, d: d}); err != nil {
+		_ = t.Rollback()
+		return err
+	}
+	return t.Commit()
+}
+
+// PutDepartments create or replace the departments.
+func (d *DB) PutDepartments(ctx context.Context, deps ...*v1alpha1.Department) error {
+	return d.inTx(ctx, func(t tx) error {
+		for _, dep := range deps {
+			if err := t.exec(ctx, "DELETE FROM departments WHERE id = ?", dep.ID); err != nil {
+				return err
+			}
+			if err := t.exec(ctx, "INSERT INTO departments (id, tenant_id, name, pid, attr) VALUES (?, ?, ?, ?, ?)",
+				dep.ID, dep.TenantID, dep.Name, dep.PID, dep.Attr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PutUsers create or replace the users with their relations, the departments
+// and the roles referenced by the users are created if not exist.
+func (d *DB) PutUsers(ctx context.Context, users ...*v1alpha1.User) error {
+	return d.inTx(ctx, func(t tx) error {
+		for _, user := range users {
+			if err := putUser(ctx, t, user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func putUser(ctx context.Context, t tx, user *v1alpha1.User) error {
+	for _, query := range []string{
+		"DELETE FROM users WHERE id = ?",
+		"DELETE FROM user_departments WHERE user_id = ?",
+		"DELETE FROM user_roles WHERE user_id = ?",
+		"DELETE FROM user_leaders WHERE user_id = ?",
+	} {
+		if err := t.exec(ctx, query, user.ID); err != nil {
+			return err
+		}
+	}
+	if err := t.exec(ctx, `INSERT INTO users (id, tenant_id, name, phone, email, created_at, job_number,
+		avatar, use_status, gender, source, self_email, position) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.TenantID, user.Name, user.Phone, user.Email, user.CreatedAt, user.JobNumber,
+		user.Avatar, user.UseStatus, user.Gender, user.Source, user.SelfEmail, user.Position); err != nil {
+		return err
+	}
+
+	for path, deps := range user.Departments {
+		for level, dep := range deps {
+			ok, err := t.exists(ctx, "departments", dep.ID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				if err := t.exec(ctx, "INSERT INTO departments (id, tenant_id, name, pid, attr) VALUES (?, ?, ?, ?, ?)",
+					dep.ID, user.TenantID, dep.Name, dep.PID, dep.Attr); err != nil {
+					return err
+				}
+			}
+			if err := t.exec(ctx, "INSERT INTO user_departments (user_id, path, level, department_id) VALUES (?, ?, ?, ?)",
+				user.ID, path, level, dep.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, role := range user.Roles {
+		ok, err := t.exists(ctx, "roles", role.ID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			err = t.exec(ctx, "UPDATE roles SET name = ? WHERE id = ?", role.Name, role.ID)
+		} else {
+			err = t.exec(ctx, "INSERT INTO roles (id, name) VALUES (?, ?)", role.ID, role.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if err := t.exec(ctx, "INSERT INTO user_roles (user_id, role_id) VALUES (?, ?)", user.ID, role.ID); err != nil {
+			return err
+		}
+	}
+
+	for path, leaders := range user.Leaders {
+		for level, leader := range leaders {
+			if err := t.exec(ctx, "INSERT INTO user_leaders (user_id, path, level, leader_id, attr) VALUES (?, ?, ?, ?, ?)",
+				user.ID, path, level, leader.ID, leader.Attr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
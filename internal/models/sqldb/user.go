@@ -0,0 +1,290 @@
+package sqldb
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+const userColumns = "u.id, u.tenant_id, u.name, u.phone, u.email, u.created_at, u.job_number, " +
+	"u.avatar, u.use_status, u.gender, u.source, u.self_email, u.position"
+
+// userOrder columns of the orderBy fields.
+var userOrder = map[string]string{
+	"id":        "u.id",
+	"name":      "u.name",
+	"phone":     "u.phone",
+	"email":     "u.email",
+	"createdAt": "u.created_at",
+	"jobNumber": "u.job_number",
+	"useStatus": "u.use_status",
+	"gender":    "u.gender",
+	"position":  "u.position",
+}
+
+type user struct {
+	db *DB
+}
+
+// NewUser return the user repo of the database.
+func NewUser(db *DB) models.UserRepo {
+	return &user{
+		db: db,
+	}
+}
+
+func (u *user) Get(ctx context.Context, userID string) (*v1alpha1.User, error) {
+	ctx, span := u.db.startSpan(ctx, "user", "get")
+	defer span.End()
+
+	users, err := u.find(ctx, " WHERE u.id = ?", userID)
+	if err != nil {
+		return nil, fail(span, err)
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return users[0], nil
+}
+
+func (u *user) List(ctx context.Context, userIDs []interface{}) ([]*v1alpha1.User, error) {
+	ctx, span := u.db.startSpan(ctx, "user", "list")
+	defer span.End()
+
+	if len(userIDs) == 0 {
+		return []*v1alpha1.User{}, nil
+	}
+	users := make([]*v1alpha1.User, 0, len(userIDs))
+	idChunks := chunks(userIDs)
+	for _, ids := range idChunks {
+		found, err := u.find(ctx, " WHERE u.id IN ("+placeholders(len(ids))+") ORDER BY u.id", ids...)
+		if err != nil {
+			return nil, fail(span, err)
+		}
+		users = append(users, found...)
+	}
+	if len(idChunks) > 1 {
+		// each chunk is ordered by id.
+		sort.Slice(users, func(i, j int) bool {
+			return users[i].ID < users[j].ID
+		})
+	}
+	return users, nil
+}
+
+func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, size int) ([]*v1alpha1.User, int64, error) {
+	ctx, span := u.db.startSpan(ctx, "user", "search")
+	defer span.End()
+
+	order, err := orderBy(query.OrderBy, userOrder, "u.name", "u.id")
+	if err != nil {
+		return nil, 0, fail(span, errdefs.Wrap(errdefs.InvalidArgument, err))
+	}
+
+	conditions, args := userConditions(query)
+	var total int64
+	err = u.db.queryRow(ctx, "SELECT COUNT(*) FROM users u"+where(conditions), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+
+	users, err := u.find(ctx, where(conditions)+order+" LIMIT ? OFFSET ?",
+		append(args, size, offset(page, size))...)
+	if err != nil {
+		return nil, 0, fail(span, err)
+	}
+	return users, total, nil
+}
+
+// userConditions filter the users like the query of the elasticsearch repo.
+func userConditions(query *v1alpha1.SearchUser) ([]string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	add := func(condition string, values ...interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if query.TenantID != "" {
+		add("u.tenant_id = ?", query.TenantID)
+	}
+	if query.DepartmentID != "" {
+		add("EXISTS (SELECT 1 FROM user_departments ud WHERE ud.user_id = u.id AND ud.department_id = ?)",
+			query.DepartmentID)
+	}
+	if query.DepartmentName != "" {
+		like, values := prefixLike("d.name", query.DepartmentName)
+		add("EXISTS (SELECT 1 FROM user_departments ud JOIN departments d ON d.id = ud.department_id "+
+			"WHERE ud.user_id = u.id AND "+like+")", values...)
+	}
+	if query.RoleID != "" {
+		add("EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id AND ur.role_id = ?)", query.RoleID)
+	}
+	if query.RoleName != "" {
+		like, values := prefixLike("r.name", query.RoleName)
+		add("EXISTS (SELECT 1 FROM user_roles ur JOIN roles r ON r.id = ur.role_id "+
+			"WHERE ur.user_id = u.id AND "+like+")", values...)
+	}
+	if query.LeaderID != "" {
+		add("EXISTS (SELECT 1 FROM user_leaders ul WHERE ul.user_id = u.id AND ul.leader_id = ?)", query.LeaderID)
+	}
+	if query.UseStatus != 0 {
+		add("u.use_status = ?", query.UseStatus)
+	}
+	if query.Gender != "" {
+		// gender is a single digit, the prefix matches the whole value.
+		gender, err := strconv.Atoi(query.Gender)
+		if err != nil {
+			add("1 = 0")
+		} else {
+			add("u.gender = ?", gender)
+		}
+	}
+
+	prefixes := []struct {
+		column, query string
+	}{
+		{"u.name", query.Name},
+		{"u.phone", query.Phone},
+		{"u.email", query.Email},
+		{"u.job_number", query.JobNumber},
+		{"u.position", query.Position},
+	}
+	for _, prefix := range prefixes {
+		if prefix.query != "" {
+			like, values := prefixLike(prefix.column, prefix.query)
+			add(like, values...)
+		}
+	}
+	return conditions, args
+}
+
+// find return the users selected by the clauses with their relations.
+func (u *user) find(ctx context.Context, clauses string, args ...interface{}) ([]*v1alpha1.User, error) {
+	rows, err := u.db.query(ctx, "SELECT "+userColumns+" FROM users u"+clauses, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*v1alpha1.User, 0)
+	for rows.Next() {
+		user := new(v1alpha1.User)
+		if err := rows.Scan(&user.ID, &user.TenantID, &user.Name, &user.Phone, &user.Email, &user.CreatedAt,
+			&user.JobNumber, &user.Avatar, &user.UseStatus, &user.Gender, &user.Source, &user.SelfEmail,
+			&user.Position); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := u.relations(ctx, users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// relations load the departments, roles and leaders of the users.
+func (u *user) relations(ctx context.Context, users []*v1alpha1.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	byID := make(map[string]*v1alpha1.User, len(users))
+	ids := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+		ids = append(ids, user.ID)
+	}
+	for _, ids := range chunks(ids) {
+		if err := u.relationsOf(ctx, byID, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relationsOf load the relations of the users of ids, at most maxParams ids.
+func (u *user) relationsOf(ctx context.Context, byID map[string]*v1alpha1.User, ids []interface{}) error {
+	in := "(" + placeholders(len(ids)) + ")"
+
+	rows, err := u.db.query(ctx, "SELECT ud.user_id, ud.path, ud.department_id, "+
+		"COALESCE(d.name, ''), COALESCE(d.pid, ''), COALESCE(d.attr, '') "+
+		"FROM user_departments ud LEFT JOIN departments d ON d.id = ud.department_id "+
+		"WHERE ud.user_id IN "+in+" ORDER BY ud.user_id, ud.path, ud.level", ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID string
+		var path int
+		dep := v1alpha1.Department{}
+		if err := rows.Scan(&userID, &path, &dep.ID, &dep.Name, &dep.PID, &dep.Attr); err != nil {
+			return err
+		}
+		user := byID[userID]
+		for len(user.Departments) <= path {
+			user.Departments = append(user.Departments, []v1alpha1.Department{})
+		}
+		user.Departments[path] = append(user.Departments[path], dep)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = u.db.query(ctx, "SELECT ur.user_id, ur.role_id, COALESCE(r.name, '') "+
+		"FROM user_roles ur LEFT JOIN roles r ON r.id = ur.role_id "+
+		"WHERE ur.user_id IN "+in+" ORDER BY ur.user_id, ur.role_id", ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID string
+		role := v1alpha1.Role{}
+		if err := rows.Scan(&userID, &role.ID, &role.Name); err != nil {
+			return err
+		}
+		byID[userID].Roles = append(byID[userID].Roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = u.db.query(ctx, "SELECT ul.user_id, ul.path, ul.leader_id, COALESCE(l.name, ''), ul.attr "+
+		"FROM user_leaders ul LEFT JOIN users l ON l.id = ul.leader_id "+
+		"WHERE ul.user_id IN "+in+" ORDER BY ul.user_id, ul.path, ul.level", ids...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID string
+		var path int
+		leader := v1alpha1.Leader{}
+		if err := rows.Scan(&userID, &path, &leader.ID, &leader.Name, &leader.Attr); err != nil {
+			return err
+		}
+		user := byID[userID]
+		for len(user.Leaders) <= path {
+			user.Leaders = append(user.Leaders, []v1alpha1.Leader{})
+		}
+		user.Leaders[path] = append(user.Leaders[path], leader)
+	}
+	return rows.Err()
+}
+
+// offset return the offset of page, page starts from 1.
+func offset(page, size int) int {
+	if page < 1 {
+		return 0
+	}
+	return (page - 1) * size
+}
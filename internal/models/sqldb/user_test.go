@@ -0,0 +1,71 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// TestListChunks list more ids than the parameters allowed by a statement of sqlite.
+func TestListChunks(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(ctx, Config{
+		Driver:  DriverSQLite,
+		DSN:     "file:" + t.Name() + "?mode=memory&cache=shared",
+		Migrate: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	n := 2*maxParams + 10
+	users := make([]*v1alpha1.User, 0, n)
+	deps := make([]*v1alpha1.Department, 0, n)
+	ids := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("%05d", i)
+		users = append(users, &v1alpha1.User{
+			ID:       id,
+			TenantID: "t1",
+			Roles:    []v1alpha1.Role{{ID: "r1", Name: "role"}},
+		})
+		deps = append(deps, &v1alpha1.Department{ID: id, TenantID: "t1"})
+		// reversed, the result is ordered by id anyway.
+		ids = append(ids, fmt.Sprintf("%05d", n-1-i))
+	}
+	if err := db.PutUsers(ctx, users...); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutDepartments(ctx, deps...); err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := NewUser(db).List(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != n {
+		t.Fatalf("list users: %d users, expect %d", len(listed), n)
+	}
+	for i, user := range listed {
+		if user.ID != users[i].ID || len(user.Roles) != 1 {
+			t.Fatalf("list users: user %d is %s with %d roles", i, user.ID, len(user.Roles))
+		}
+	}
+
+	listedDeps, err := NewDepartment(db).List(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listedDeps) != n {
+		t.Fatalf("list departments: %d departments, expect %d", len(listedDeps), n)
+	}
+	for i, dep := range listedDeps {
+		if dep.ID != deps[i].ID {
+			t.Fatalf("list departments: department %d is %s", i, dep.ID)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package stale
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+type user struct {
+	repo  models.UserRepo
+	stale *Stale
+}
+
+// NewUser return the user repo serving the last known good results of repo
+// while the storage is down.
+func NewUser(repo models.UserRepo, stale *Stale) models.UserRepo {
+	return &user{
+		repo:  repo,
+		stale: stale,
+	}
+}
+
+func (u *user) Get(ctx context.Context, userID string) (user *v1alpha1.User, err error) {
+	key := u.stale.key(ctx, KindUser, models.TenantFromContext(ctx), "get", userID)
+	err = u.stale.do(ctx, KindUser, "get", key, &user, func(ctx context.Context) error {
+		user, err = u.repo.Get(ctx, userID)
+		return err
+	})
+	return user, err
+}
+
+func (u *user) List(ctx context.Context, userIDs []interface{}) (users []*v1alpha1.User, err error) {
+	key := u.stale.key(ctx, KindUser, models.TenantFromContext(ctx), "list", userIDs)
+	err = u.stale.do(ctx, KindUser, "list", key, &users, func(ctx context.Context) error {
+		users, err = u.repo.List(ctx, userIDs)
+		return err
+	})
+	return users, err
+}
+
+type searchResult struct {
+	Users       []*v1alpha1.User       `json:"users,omitempty"`
+	Departments []*v1alpha1.Department `json:"departments,omitempty"`
+	Total       int64                  `json:"total"`
+}
+
+func (u *user) Search(ctx context.Context, query *v1alpha1.SearchUser, page, size int) ([]*v1alpha1.User, int64, error) {
+	key := u.stale.key(ctx, KindUser, query.TenantID, "search", query, page, size)
+	var result searchResult
+	err := u.stale.do(ctx, KindUser, "search", key, &result, func(ctx context.Context) (err error) {
+		result.Users, result.Total, err = u.repo.Search(ctx, query, page, size)
+		return err
+	})
+	return result.Users, result.Total, err
+}
+
+type department struct {
+	repo  models.DepartmentRepo
+	stale *Stale
+}
+
+// NewDepartment return the department repo serving the last known good results of repo
+// while the storage is down.
+func NewDepartment(repo models.DepartmentRepo, stale *Stale) models.DepartmentRepo {
+	return &department{
+		repo:  repo,
+		stale: stale,
+	}
+}
+
+func (d *department) Search(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) ([]*v1alpha1.Department, int64, error) {
+	key := d.stale.key(ctx, KindDepartment, query.TenantID, "search", query, page, size)
+	var result searchResult
+	err := d.stale.do(ctx, KindDepartment, "search", key, &result, func(ctx context.Context) (err error) {
+		result.Departments, result.Total, err = d.repo.Search(ctx, query, page, size)
+		return err
+	})
+	return result.Departments, result.Total, err
+}
+
+func (d *department) List(ctx context.Context, depIDs []interface{}) (deps []*v1alpha1.Department, err error) {
+	key := d.stale.key(ctx, KindDepartment, models.TenantFromContext(ctx), "list", depIDs)
+	err = d.stale.do(ctx, KindDepartment, "list", key, &deps, func(ctx context.Context) error {
+		deps, err = d.repo.List(ctx, depIDs)
+		return err
+	})
+	return deps, err
+}
@@ -0,0 +1,179 @@
+package stale
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/models/cache"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// kinds of the repos
+const (
+	KindUser       = "user"
+	KindDepartment = "department"
+)
+
+const keyPrefix = "search:stale:"
+
+// Config stale-while-error config
+type Config struct {
+	// Enabled serve the last known good results while the storage is down
+	Enabled bool
+	// TTL max age of the served results, default 24h
+	TTL time.Duration
+	// Size max results kept by each instance, default 10000
+	Size int
+}
+
+// Stale snapshot of the last known good results of the repos, kept in memory
+// of each instance. The results are served, marked as stale, if the storage
+// is unavailable or timed out. The snapshot outlives the cache entries by its TTL,
+// but Invalidate drops the results of the tenant like the cache does,
+// so the results are never older than the last write of the tenant.
+type Stale struct {
+	log   logr.Logger
+	store cache.Store
+	ttl   time.Duration
+}
+
+// New return the snapshot of the config, nil if disabled.
+func New(ctx context.Context, conf Config) *Stale {
+	if !conf.Enabled {
+		return nil
+	}
+	size := conf.Size
+	if size <= 0 {
+		size = 10000
+	}
+	return NewWithStore(ctx, cache.NewMemory(size), conf.TTL)
+}
+
+// NewWithStore return the snapshot backed by store, ttl is 24h if not positive.
+func NewWithStore(ctx context.Context, store cache.Store, ttl time.Duration) *Stale {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &Stale{
+		log:   util.LoggerFromContext(ctx).WithName("stale"),
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Invalidate drop the results of the tenant of kind, it is called
+// after the documents of the tenant are written. The results of the queries
+// across tenants are dropped too.
+func (s *Stale) Invalidate(ctx context.Context, kind, tenantID string) error {
+	tenants := []string{""}
+	if tenantID != "" {
+		tenants = append(tenants, tenantID)
+	}
+	for _, tenantID := range tenants {
+		if _, err := s.store.Incr(ctx, generationKey(kind, tenantID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close close the store.
+func (s *Stale) Close() error {
+	return s.store.Close()
+}
+
+// key return the key of the method called with args,
+// empty if args are not encodable or the generation is not available.
+func (s *Stale) key(ctx context.Context, kind, tenantID, method string, args ...interface{}) string {
+	generation, err := s.store.Counter(ctx, generationKey(kind, tenantID))
+	if err != nil {
+		util.LoggerWithRequest(ctx, s.log).Error(err, "get generation", "kind", kind)
+		return ""
+	}
+
+	body, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return keyPrefix + kind + ":" + tenantID + ":" + strconv.FormatInt(generation, 10) + ":" +
+		method + ":" + hex.EncodeToString(sum[:])
+}
+
+// do call fn which sets the result into dst, the complete result is saved as the
+// last known good one of key. If fn failed by the storage down, the saved result
+// is decoded into dst and the results are marked as stale.
+func (s *Stale) do(ctx context.Context, kind, method, key string, dst interface{}, fn func(ctx context.Context) error) error {
+	partialCtx, meta := models.WithMeta(ctx)
+	err := fn(partialCtx)
+	if meta.Partial() {
+		models.MetaFromContext(ctx).SetPartial()
+	}
+	if err == nil {
+		if !meta.Partial() {
+			s.save(ctx, key, dst)
+		}
+		return nil
+	}
+
+	if !down(err) || errors.Is(ctx.Err(), context.Canceled) || !s.load(ctx, key, dst) {
+		return err
+	}
+	util.LoggerWithRequest(ctx, s.log).Info("serve stale result", "kind", kind, "method", method,
+		"error", err.Error())
+	metrics.ObserveStale(kind, method)
+	models.MetaFromContext(ctx).SetStale()
+	return nil
+}
+
+// load decode the result of key into dst, false if miss.
+func (s *Stale) load(ctx context.Context, key string, dst interface{}) bool {
+	if key == "" {
+		return false
+	}
+	value, ok, err := s.store.Get(ctx, key)
+	if err != nil {
+		util.LoggerWithRequest(ctx, s.log).Error(err, "get")
+		return false
+	}
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(value, dst) == nil
+}
+
+func (s *Stale) save(ctx context.Context, key string, value interface{}) {
+	if key == "" {
+		return
+	}
+	body, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := s.store.Set(ctx, key, body, s.ttl); err != nil {
+		util.LoggerWithRequest(ctx, s.log).Error(err, "set")
+	}
+}
+
+// down report whether err means the storage is unreachable or overloaded,
+// including the calls failed fast by the circuit breaker.
+func down(err error) bool {
+	switch errdefs.From(err).Code {
+	case errdefs.Unavailable, errdefs.Timeout:
+		return true
+	}
+	return false
+}
+
+func generationKey(kind, tenantID string) string {
+	return keyPrefix + "generation:" + kind + ":" + tenantID
+}
@@ -0,0 +1,156 @@
+package stale
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/models/cache"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+func newTestStale() *Stale {
+	return NewWithStore(context.Background(), cache.NewMemory(100), 0)
+}
+
+// get call do for the key of the tenant with fn setting value,
+// the result and the meta of the call are returned.
+func get(s *Stale, tenantID string, fn func(ctx context.Context) (string, error)) (string, *models.Meta, error) {
+	ctx, meta := models.WithMeta(context.Background())
+	var value string
+	key := s.key(ctx, KindUser, tenantID, "get", "u1")
+	err := s.do(ctx, KindUser, "get", key, &value, func(ctx context.Context) (err error) {
+		value, err = fn(ctx)
+		return err
+	})
+	return value, meta, err
+}
+
+func succeed(value string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		return value, nil
+	}
+}
+
+func fail(err error) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		return "", err
+	}
+}
+
+func TestDo(t *testing.T) {
+	unavailable := errdefs.NewUnavailable("unavailable")
+	timeout := errdefs.New(errdefs.Timeout, "timeout")
+	notFound := errdefs.NewNotFound("not found")
+
+	s := newTestStale()
+	if _, _, err := get(s, "t1", fail(unavailable)); err != unavailable {
+		t.Errorf("down without snapshot: %v", err)
+	}
+
+	value, meta, err := get(s, "t1", succeed("v1"))
+	if err != nil || value != "v1" || meta.Stale() {
+		t.Fatalf("succeeded: %q, stale %v, %v", value, meta.Stale(), err)
+	}
+
+	for _, down := range []error{unavailable, timeout} {
+		value, meta, err = get(s, "t1", fail(down))
+		if err != nil || value != "v1" || !meta.Stale() {
+			t.Errorf("%v: %q, stale %v, %v", down, value, meta.Stale(), err)
+		}
+	}
+
+	// the storage did answer
+	for _, answered := range []error{notFound, errors.New("internal")} {
+		value, meta, err = get(s, "t1", fail(answered))
+		if err != answered || value != "" || meta.Stale() {
+			t.Errorf("%v: %q, stale %v, %v", answered, value, meta.Stale(), err)
+		}
+	}
+
+	// the snapshot is kept by tenant
+	if _, _, err := get(s, "t2", fail(unavailable)); err != unavailable {
+		t.Errorf("down without snapshot of the tenant: %v", err)
+	}
+}
+
+func TestDoPartial(t *testing.T) {
+	unavailable := errdefs.NewUnavailable("unavailable")
+	s := newTestStale()
+
+	if _, _, err := get(s, "t1", succeed("v1")); err != nil {
+		t.Fatal(err)
+	}
+	value, meta, err := get(s, "t1", func(ctx context.Context) (string, error) {
+		models.MetaFromContext(ctx).SetPartial()
+		return "v2", nil
+	})
+	if err != nil || value != "v2" || !meta.Partial() || meta.Stale() {
+		t.Errorf("partial: %q, partial %v, stale %v, %v", value, meta.Partial(), meta.Stale(), err)
+	}
+
+	// the partial result is not saved
+	value, meta, err = get(s, "t1", fail(unavailable))
+	if err != nil || value != "v1" || !meta.Stale() || meta.Partial() {
+		t.Errorf("down after partial: %q, partial %v, stale %v, %v", value, meta.Partial(), meta.Stale(), err)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	s := newTestStale()
+	if _, _, err := get(s, "t1", succeed("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ctx, meta := models.WithMeta(ctx)
+	var value string
+	key := s.key(ctx, KindUser, "t1", "get", "u1")
+	err := s.do(ctx, KindUser, "get", key, &value, func(ctx context.Context) error {
+		return errdefs.NewUnavailable("%s", ctx.Err())
+	})
+	if err == nil || value != "" || meta.Stale() {
+		t.Errorf("canceled: %q, stale %v, %v", value, meta.Stale(), err)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	unavailable := errdefs.NewUnavailable("unavailable")
+	s := newTestStale()
+	ctx := context.Background()
+
+	for _, tenantID := range []string{"t1", "t2", ""} {
+		if _, _, err := get(s, tenantID, succeed("v1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Invalidate(ctx, KindDepartment, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _, err := get(s, "t1", fail(unavailable)); err != nil || value != "v1" {
+		t.Errorf("other kind invalidated: %q, %v", value, err)
+	}
+
+	if err := s.Invalidate(ctx, KindUser, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := get(s, "t1", fail(unavailable)); err != unavailable {
+		t.Errorf("invalidated tenant: %v", err)
+	}
+	if _, _, err := get(s, "", fail(unavailable)); err != unavailable {
+		t.Errorf("invalidated across tenants: %v", err)
+	}
+	if value, _, err := get(s, "t2", fail(unavailable)); err != nil || value != "v1" {
+		t.Errorf("other tenant: %q, %v", value, err)
+	}
+
+	// the results saved after the invalidation are served
+	if _, _, err := get(s, "t1", succeed("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if value, _, err := get(s, "t1", fail(unavailable)); err != nil || value != "v2" {
+		t.Errorf("saved after invalidation: %q, %v", value, err)
+	}
+}
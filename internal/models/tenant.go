@@ -0,0 +1,17 @@
+package models
+
+import "context"
+
+type tenantKey struct{}
+
+// WithTenant return a context carrying the tenant of the request,
+// the repos route the requests by it.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext return the tenant of the request, empty if not exist.
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey{}).(string)
+	return tenantID
+}
@@ -0,0 +1,325 @@
+// Package openapi OpenAPI 3 document of the http api, the schemas and
+// the query parameters are generated from the go types by reflection.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Version version of the OpenAPI specification.
+const Version = "3.0.3"
+
+// Document OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info info of the api.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem operations of a path.
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
+}
+
+// operation return the field of the operation of the method, nil if OpenAPI has no such method.
+func (p *PathItem) operation(method string) **Operation {
+	switch method {
+	case http.MethodGet:
+		return &p.Get
+	case http.MethodPut:
+		return &p.Put
+	case http.MethodPost:
+		return &p.Post
+	case http.MethodDelete:
+		return &p.Delete
+	case http.MethodOptions:
+		return &p.Options
+	case http.MethodHead:
+		return &p.Head
+	case http.MethodPatch:
+		return &p.Patch
+	case http.MethodTrace:
+		return &p.Trace
+	}
+	return nil
+}
+
+// Supported report whether OpenAPI can describe the method, CONNECT can not.
+func Supported(method string) bool {
+	return (&PathItem{}).operation(method) != nil
+}
+
+// Operation operation of a method on a path.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Security names of the security schemes required by the operation
+	Security []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Explode     *bool   `json:"explode,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody body of an operation.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response response of an operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType content of a media type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components reusable objects of the document.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme security scheme of the operations.
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Schema schema object, a subset of the specification.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+}
+
+// New return the empty document.
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+}
+
+// Add add the operation of the method on the path, the methods not Supported
+// are ignored, the gin path parameters like :id are turned into {id}.
+func (d *Document) Add(method, path string, op *Operation) {
+	path = ginPath(path)
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	if field := item.operation(method); field != nil {
+		*field = op
+	}
+}
+
+// Operation return the operation of the method on the path, nil if absent.
+func (d *Document) Operation(method, path string) *Operation {
+	item, ok := d.Paths[ginPath(path)]
+	if !ok {
+		return nil
+	}
+	if field := item.operation(method); field != nil {
+		return *field
+	}
+	return nil
+}
+
+func ginPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Schema return the schema of the type of v, the named structs are
+// registered as the components and referred by $ref.
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.object(t)
+		}
+		name := t.Name()
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// placeholder first, the type may refer to itself.
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.object(t)
+		}
+		return Ref(name)
+	}
+	// interface{}, any value
+	return &Schema{}
+}
+
+func (d *Document) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts := tagName(f.Tag.Get("json"))
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := d.schema(f.Type)
+			if embedded.Ref != "" {
+				embedded = d.Components.Schemas[strings.TrimPrefix(embedded.Ref, refPrefix)]
+			}
+			for name, prop := range embedded.Properties {
+				s.Properties[name] = prop
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = d.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// Parameters return the query parameters bound from the form tags of the struct of v
+// like gin binding, the fields of the form tag - are skipped.
+func (d *Document) Parameters(v interface{}) []*Parameter {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	params := make([]*Parameter, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts := tagName(f.Tag.Get("form"))
+		if name == "-" || name == "" {
+			continue
+		}
+		param := &Parameter{
+			Name:   name,
+			In:     "query",
+			Schema: d.schema(f.Type),
+		}
+		if param.Schema.Type == "array" {
+			explode := true
+			param.Explode = &explode
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			if strings.HasPrefix(opt, "default=") {
+				param.Schema.Default = defaultValue(param.Schema, strings.TrimPrefix(opt, "default="))
+			}
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// defaultValue return the default value of the form tag typed by the schema.
+func defaultValue(s *Schema, value string) interface{} {
+	switch s.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+const refPrefix = "#/components/schemas/"
+
+// Ref return the reference to the component schema of name.
+func Ref(name string) *Schema {
+	return &Schema{Ref: refPrefix + name}
+}
+
+func tagName(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+const (
+	defaultMaxDepth      = 10
+	defaultMaxComplexity = 20000
+)
+
+// fieldCosts cost of the field which is more expensive than a plain field,
+// keyed by "type.field".
+var fieldCosts = map[string]int{
+	// get whoami and then list the leaders.
+	"_leader.query": 2,
+}
+
+// Limits limits of a single query.
+type Limits struct {
+	// MaxDepth the max depth of the selection set.
+	MaxDepth int
+	// MaxComplexity the max complexity of the query, each selected field costs 1
+	// and each list costs the documents it fetches, by the size or the ids argument.
+	MaxComplexity int
+}
+
+func (l Limits) withDefault() Limits {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = defaultMaxDepth
+	}
+	if l.MaxComplexity <= 0 {
+		l.MaxComplexity = defaultMaxComplexity
+	}
+	return l
+}
+
+// analyze reject the query if over the limits.
+func (l Limits) analyze(schema graphql.Schema, doc *ast.Document) error {
+	c := &complexity{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		// the data is bound from the only root field, reject before executing.
+		if n := c.rootFields(op.SelectionSet, nil); n > 1 {
+			return errdefs.NewInvalidArgument("only one root field is allowed, got %d", n)
+		}
+
+		depth, cost := c.selectionSet(schema.QueryType(), op.SelectionSet, 1, nil)
+		if depth > l.MaxDepth {
+			return errdefs.NewInvalidArgument("query depth %d exceeds the max depth %d", depth, l.MaxDepth)
+		}
+		if cost > l.MaxComplexity {
+			return errdefs.NewInvalidArgument("query complexity %d exceeds the max complexity %d", cost, l.MaxComplexity)
+		}
+	}
+
+	return nil
+}
+
+type complexity struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// selectionSet return the depth and the cost of the selection set,
+// visited protects against cyclic fragments.
+func (c *complexity) selectionSet(parent graphql.Type, set *ast.SelectionSet, depth int, visited map[string]bool) (int, int) {
+	if set == nil {
+		return depth - 1, 0
+	}
+
+	maxDepth, cost := depth, 0
+	for _, selection := range set.Selections {
+		var (
+			d, n int
+		)
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, n = c.field(parent, selection, depth, visited)
+		case *ast.InlineFragment:
+			typ := parent
+			if selection.TypeCondition != nil {
+				typ = c.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			d, n = c.selectionSet(typ, selection.SelectionSet, depth, visited)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok || visited[name] {
+				continue
+			}
+			next := make(map[string]bool, len(visited)+1)
+			for k := range visited {
+				next[k] = true
+			}
+			next[name] = true
+			d, n = c.selectionSet(c.schema.Type(fragment.TypeCondition.Name.Value),
+				fragment.SelectionSet, depth, next)
+		}
+
+		if d > maxDepth {
+			maxDepth = d
+		}
+		cost += n
+	}
+
+	return maxDepth, cost
+}
+
+// rootFields return the number of the fields of the selection set, the fragments are expanded,
+// visited protects against cyclic fragments.
+func (c *complexity) rootFields(set *ast.SelectionSet, visited map[string]bool) int {
+	if set == nil {
+		return 0
+	}
+
+	n := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			n++
+		case *ast.InlineFragment:
+			n += c.rootFields(selection.SelectionSet, visited)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+			if !ok || visited[name] {
+				continue
+			}
+			next := make(map[string]bool, len(visited)+1)
+			for k := range visited {
+				next[k] = true
+			}
+			next[name] = true
+			n += c.rootFields(fragment.SelectionSet, next)
+		}
+	}
+	return n
+}
+
+func (c *complexity) field(parent graphql.Type, field *ast.Field, depth int, visited map[string]bool) (int, int) {
+	var (
+		def  *graphql.FieldDefinition
+		typ  graphql.Type
+		cost = 1
+	)
+	if object, ok := parent.(*graphql.Object); ok {
+		def = object.Fields()[field.Name.Value]
+		if n, ok := fieldCosts[object.Name()+"."+field.Name.Value]; ok {
+			cost = n
+		}
+	}
+	if def != nil {
+		typ, _ = graphql.GetNamed(def.Type).(graphql.Type)
+	}
+
+	d, children := c.selectionSet(typ, field.SelectionSet, depth+1, visited)
+
+	return d, cost + documents(def, field) + children
+}
+
+// documents return the number of the documents fetched by the field,
+// according to the size or the ids argument, zero if the field fetches no list.
+func documents(def *graphql.FieldDefinition, field *ast.Field) int {
+	if def == nil {
+		return 0
+	}
+
+	for _, arg := range field.Arguments {
+		switch arg.Name.Value {
+		case "size":
+			if value, ok := arg.Value.(*ast.IntValue); ok {
+				var size int
+				fmt.Sscan(value.Value, &size)
+				return pageSize(size)
+			}
+		case "ids":
+			if value, ok := arg.Value.(*ast.ListValue); ok && len(value.Values) > 0 {
+				return len(value.Values)
+			}
+		}
+	}
+
+	for _, arg := range def.Args {
+		if arg.Name() == "size" {
+			size, _ := arg.DefaultValue.(int)
+			return pageSize(size)
+		}
+	}
+
+	return 0
+}
+
+func pageSize(size int) int {
+	if size <= 0 {
+		return maxSize
+	}
+	return size
+}
@@ -1,11 +1,13 @@
 package service
 
 import (
-	"errors"
 	"github.com/go-logr/logr"
 	"github.com/graphql-go/graphql"
 	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/tracing"
 	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/util"
 )
 
 var DepartmentInfo = graphql.NewObject(
@@ -73,7 +75,7 @@ func (u *department) resolve(p graphql.ResolveParams) (interface{}, error) {
 	}
 	err := mapToStruct(query, p.Args)
 	if err != nil {
-		u.log.Error(err, "bind args")
+		util.LoggerWithRequest(p.Context, u.log).Error(err, "bind args")
 		return nil, err
 	}
 	page, size := bindPageSize(p.Args)
@@ -82,7 +84,7 @@ func (u *department) resolve(p graphql.ResolveParams) (interface{}, error) {
 		page, size,
 	)
 	if err != nil {
-		u.log.Error(err, "search department")
+		util.LoggerWithRequest(p.Context, u.log).Error(err, "search department")
 		return nil, err
 	}
 
@@ -111,7 +113,7 @@ func (u *department) query() error {
 						},
 					},
 					),
-					Resolve: u.resolve,
+					Resolve: tracing.Resolve("department.resolve", u.resolve),
 				},
 			},
 		}),
@@ -138,7 +140,7 @@ func (u *department) getByIDs() error {
 							Type: graphql.NewList(graphql.String),
 						},
 					},
-					Resolve: u.getByIDsResolve,
+					Resolve: tracing.Resolve("department.getByIDsResolve", u.getByIDsResolve),
 				},
 			},
 		}),
@@ -154,11 +156,11 @@ func (u *department) getByIDs() error {
 func (u *department) getByIDsResolve(p graphql.ResolveParams) (interface{}, error) {
 	ids, ok := p.Args["ids"].([]interface{})
 	if !ok {
-		return nil, errors.New("invalid id type")
+		return nil, errdefs.NewInvalidArgument("invalid id type")
 	}
 	list, err := u.depRepo.List(p.Context, ids)
 	if err != nil {
-		u.log.Error(err, "search department")
+		util.LoggerWithRequest(p.Context, u.log).Error(err, "search department")
 		return nil, err
 	}
 	return struct {
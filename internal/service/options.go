@@ -2,17 +2,106 @@ package service
 
 import (
 	"context"
+	"time"
 
-	"github.com/olivere/elastic/v7"
+	"github.com/quanxiang-cloud/search/internal/models/bleve"
+	"github.com/quanxiang-cloud/search/internal/models/cache"
 	"github.com/quanxiang-cloud/search/internal/models/elasticsearch"
+	"github.com/quanxiang-cloud/search/internal/models/elasticsearch/engine"
+	"github.com/quanxiang-cloud/search/internal/models/memory"
+	"github.com/quanxiang-cloud/search/internal/models/resilience"
+	"github.com/quanxiang-cloud/search/internal/models/sqldb"
+	"github.com/quanxiang-cloud/search/internal/models/stale"
 )
 
 // Option option
 type Option func(*Search)
 
-func WithES(ctx context.Context, client *elastic.Client) Option {
+func WithES(ctx context.Context, e engine.Engine, opts ...elasticsearch.Option) Option {
 	return func(s *Search) {
-		s.userRepo = elasticsearch.NewUser(ctx, client)
-		s.depRepo = elasticsearch.NewDepartment(ctx, client)
+		s.userRepo = elasticsearch.NewUser(ctx, e, opts...)
+		s.depRepo = elasticsearch.NewDepartment(ctx, e, opts...)
+	}
+}
+
+// WithBleve use the repos of the embedded bleve index.
+func WithBleve(index *bleve.Index) Option {
+	return func(s *Search) {
+		s.userRepo = bleve.NewUser(index)
+		s.depRepo = bleve.NewDepartment(index)
+	}
+}
+
+// WithMemory use the in-memory repos of the store.
+func WithMemory(store *memory.Store) Option {
+	return func(s *Search) {
+		s.userRepo = memory.NewUser(store)
+		s.depRepo = memory.NewDepartment(store)
+	}
+}
+
+// WithSQL use the sql repos of the database.
+func WithSQL(db *sqldb.DB) Option {
+	return func(s *Search) {
+		s.userRepo = sqldb.NewUser(db)
+		s.depRepo = sqldb.NewDepartment(db)
+	}
+}
+
+// WithResilience retry and break the calls of the repos, must be after the repos are set
+// and before the cache, so the cache hits are not counted, nil resilience is ignored.
+func WithResilience(r *resilience.Resilience) Option {
+	return func(s *Search) {
+		if r == nil {
+			return
+		}
+		s.userRepo = resilience.NewUser(s.userRepo, r)
+		s.depRepo = resilience.NewDepartment(s.depRepo, r)
+	}
+}
+
+// WithStale serve the last known good results of the repos while the storage is down,
+// must be after the resilience and before the cache, nil stale is ignored.
+func WithStale(st *stale.Stale) Option {
+	return func(s *Search) {
+		if st == nil {
+			return
+		}
+		s.userRepo = stale.NewUser(s.userRepo, st)
+		s.depRepo = stale.NewDepartment(s.depRepo, st)
+	}
+}
+
+// WithCache cache the results of the repos, must be after the repos are set,
+// nil cache is ignored.
+func WithCache(c *cache.Cache) Option {
+	return func(s *Search) {
+		if c == nil {
+			return
+		}
+		s.userRepo = cache.NewUser(s.userRepo, c)
+		s.depRepo = cache.NewDepartment(s.depRepo, c)
+	}
+}
+
+// WithLimits set the depth and complexity limits of the query,
+// zero means the default limit.
+func WithLimits(limits Limits) Option {
+	return func(s *Search) {
+		s.limits = limits.withDefault()
+	}
+}
+
+// WithPersistedQuery set the persisted queries.
+func WithPersistedQuery(opt PersistedQuery) Option {
+	return func(s *Search) {
+		s.persisted = newPersisted(opt)
+	}
+}
+
+// WithSlowThreshold log the queries slower than threshold, zero means never.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(s *Search) {
+		s.slowThreshold = threshold
 	}
 }
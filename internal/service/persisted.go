@@ -0,0 +1,124 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+const (
+	// maxAutoPersisted the max number of the queries registered by clients,
+	// protects the memory from untrusted clients.
+	maxAutoPersisted = 1000
+)
+
+// error codes of the Automatic Persisted Queries protocol, the extensions.code of the errors.
+const (
+	persistedQueryNotFound     = "PERSISTED_QUERY_NOT_FOUND"
+	persistedQueryNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+	persistedQueryHashMismatch = "PERSISTED_QUERY_HASH_MISMATCH"
+)
+
+// PersistedQuery persisted query options.
+type PersistedQuery struct {
+	// Queries hash to query text.
+	Queries map[string]string
+	// Enforce only allow the registered hashes,
+	// arbitrary query strings are rejected.
+	Enforce bool
+}
+
+type persisted struct {
+	mu      sync.RWMutex
+	queries map[string]string
+	// auto number of the queries registered by clients.
+	auto    int
+	enforce bool
+}
+
+func newPersisted(opt PersistedQuery) *persisted {
+	p := &persisted{
+		queries: make(map[string]string, len(opt.Queries)),
+		enforce: opt.Enforce,
+	}
+	for hash, query := range opt.Queries {
+		p.queries[strings.ToLower(hash)] = query
+	}
+	return p
+}
+
+// resolve return the query text to be executed.
+func (p *persisted) resolve(query, hash string) (string, error) {
+	if hash == "" {
+		if p.enforce {
+			return "", persistedError("only persisted queries are allowed", persistedQueryNotSupported)
+		}
+		return query, nil
+	}
+
+	hash = strings.ToLower(hash)
+	p.mu.RLock()
+	stored, ok := p.queries[hash]
+	p.mu.RUnlock()
+	if ok {
+		return stored, nil
+	}
+
+	if query == "" {
+		return "", persistedError("PersistedQueryNotFound", persistedQueryNotFound)
+	}
+	if p.enforce {
+		return "", persistedError("query is not in the allow-list", persistedQueryNotSupported)
+	}
+	if QueryHash(query) != hash {
+		return "", persistedError("provided sha does not match query", persistedQueryHashMismatch)
+	}
+
+	p.mu.Lock()
+	if p.auto < maxAutoPersisted {
+		if _, ok := p.queries[hash]; !ok {
+			p.queries[hash] = query
+			p.auto++
+		}
+	}
+	p.mu.Unlock()
+
+	return query, nil
+}
+
+func persistedError(message, code string) error {
+	return errdefs.NewInvalidArgument("%s", message).WithExtensionCode(code)
+}
+
+// QueryHash return the sha256 hash of the query in hex.
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadPersistedQueries load the queries from *.graphql files of the dir,
+// the queries are keyed by the sha256 hash of the raw file content,
+// which is the same query text sent by the clients.
+func LoadPersistedQueries(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.graphql"))
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make(map[string]string, len(files))
+	for _, file := range files {
+		body, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		query := string(body)
+		queries[QueryHash(query)] = query
+	}
+
+	return queries, nil
+}
@@ -6,25 +6,39 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/gqlerrors"
 	"github.com/graphql-go/graphql/language/ast"
-	"github.com/quanxiang-cloud/cabin/tailormade/header"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/quanxiang-cloud/search/internal/metrics"
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
 	"github.com/quanxiang-cloud/search/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Search struct {
 	log logr.Logger
 
+	limits    Limits
+	persisted *persisted
+	// slowThreshold queries slower than it are logged, zero means never
+	slowThreshold time.Duration
+
 	user
 	department
 }
 
 func NewSearch(ctx context.Context, opts ...Option) (*Search, error) {
 	search := &Search{
-		log: util.LoggerFromContext(ctx).WithName("search"),
+		log:       util.LoggerFromContext(ctx).WithName("search"),
+		limits:    Limits{}.withDefault(),
+		persisted: newPersisted(PersistedQuery{}),
 	}
 
 	err := search.newSchema()
@@ -52,42 +66,88 @@ type base struct {
 	DepartmentID string `json:"departmentID,omitempty"`
 	TenantID     string `json:"tenantID,omitempty"`
 	Query        string `json:"query,omitempty"`
+	// Hash sha256 hash of the persisted query.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Result common part of the responses.
+type Result struct {
+	// Errors errors of the query, returned along with the data
+	// if part of the query is resolved.
+	Errors []gqlerrors.FormattedError
+	// Partial true if the results are incomplete,
+	// e.g. some shards of elasticsearch timed out.
+	Partial bool
+	// Stale true if the results are the last known good ones
+	// served while the storage is down.
+	Stale bool
+}
+
+// User projection of v1alpha1.User, the fields not selected by the query are omitted.
+type User struct {
+	v1alpha1.User
+	// TenantID empty if not selected.
+	TenantID string `json:"tenantID,omitempty"`
+	// Departments arranged from the current user's department
+	// to the top-level department.
+	Departments [][]Department `json:"departments,omitempty"`
+}
+
+// Department projection of v1alpha1.Department, the fields not selected by the query are omitted,
+// attr is a number in the schemas.
+type Department struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	PID       string `json:"pid,omitempty"`
+	Attr      int    `json:"attr,omitempty"`
+	UseStatus int    `json:"useStatus,omitempty"`
+	TenantID  string `json:"tenantID,omitempty"`
+}
+
+// Users users of the query.
+type Users struct {
+	// Total nil if not selected.
+	Total *int64 `json:"total,omitempty"`
+	// Users nil if not selected.
+	Users *[]User `json:"users,omitempty"`
+}
+
+// Departments departments of the query.
+type Departments struct {
+	// Total nil if not selected.
+	Total *int64 `json:"total,omitempty"`
+	// Departments nil if not selected.
+	Departments *[]Department `json:"departments,omitempty"`
 }
 
 type SearchUserReq struct {
 	base
 }
 type SearchUserResp struct {
-	Data interface{}
+	Users *Users
+	Result
 }
 
 type SearchDepartmentReq struct {
 	base
 }
 type SearchDepartmentResp struct {
-	Data interface{}
+	Departments *Departments
+	Result
 }
 
 func (s *Search) SearchUser(ctx context.Context, req *SearchUserReq) (*SearchUserResp, error) {
-	data, err := s.search(ctx, s.user.querySchema, req.base)
-	if err != nil {
-		return &SearchUserResp{}, err
-	}
-
-	return &SearchUserResp{
-		Data: data,
-	}, nil
+	resp := &SearchUserResp{}
+	result, err := s.search(ctx, s.user.querySchema, req.base, &resp.Users)
+	resp.Result = result
+	return resp, err
 }
 
 func (s *Search) SearchDepartment(ctx context.Context, req *SearchDepartmentReq) (*SearchDepartmentResp, error) {
-	data, err := s.search(ctx, s.department.querySchema, req.base)
-	if err != nil {
-		return &SearchDepartmentResp{}, err
-	}
-
-	return &SearchDepartmentResp{
-		Data: data,
-	}, nil
+	resp := &SearchDepartmentResp{}
+	result, err := s.search(ctx, s.department.querySchema, req.base, &resp.Departments)
+	resp.Result = result
+	return resp, err
 }
 
 type DepartmentsByIDsReq struct {
@@ -95,18 +155,15 @@ type DepartmentsByIDsReq struct {
 }
 
 type DepartmentsByIDsResp struct {
-	Data interface{}
+	Departments *Departments
+	Result
 }
 
-func (s *Search) DepartmentByIDs(ctx context.Context, req *DepartmentsByIDsReq) (*DepartmentsByIDsResp, interface{}) {
-	data, err := s.search(ctx, s.department.queryByIDsSchema, req.base)
-	if err != nil {
-		return &DepartmentsByIDsResp{}, err
-	}
-
-	return &DepartmentsByIDsResp{
-		Data: data,
-	}, nil
+func (s *Search) DepartmentByIDs(ctx context.Context, req *DepartmentsByIDsReq) (*DepartmentsByIDsResp, error) {
+	resp := &DepartmentsByIDsResp{}
+	result, err := s.search(ctx, s.department.queryByIDsSchema, req.base, &resp.Departments)
+	resp.Result = result
+	return resp, err
 }
 
 type DepartmentMemberReq struct {
@@ -114,18 +171,15 @@ type DepartmentMemberReq struct {
 }
 
 type DepartmentMemberResp struct {
-	Data interface{}
+	Users *Users
+	Result
 }
 
 func (s *Search) DepartmentMember(ctx context.Context, req *DepartmentMemberReq) (*DepartmentMemberResp, error) {
-	data, err := s.search(ctx, s.departmentMemberSchema, req.base)
-	if err != nil {
-		return &DepartmentMemberResp{}, err
-	}
-
-	return &DepartmentMemberResp{
-		Data: data,
-	}, nil
+	resp := &DepartmentMemberResp{}
+	result, err := s.search(ctx, s.departmentMemberSchema, req.base, &resp.Users)
+	resp.Result = result
+	return resp, err
 }
 
 type SubordinateReq struct {
@@ -133,18 +187,15 @@ type SubordinateReq struct {
 }
 
 type SubordinateResp struct {
-	Data interface{}
+	Users *Users
+	Result
 }
 
 func (s *Search) Subordinate(ctx context.Context, req *SubordinateReq) (*SubordinateResp, error) {
-	data, err := s.search(ctx, s.subordinateSchema, req.base)
-	if err != nil {
-		return &SubordinateResp{}, err
-	}
-
-	return &SubordinateResp{
-		Data: data,
-	}, nil
+	resp := &SubordinateResp{}
+	result, err := s.search(ctx, s.subordinateSchema, req.base, &resp.Users)
+	resp.Result = result
+	return resp, err
 }
 
 type LeaderReq struct {
@@ -152,18 +203,15 @@ type LeaderReq struct {
 }
 
 type LeaderResp struct {
-	Data interface{}
+	Leaders []User
+	Result
 }
 
 func (s *Search) Leader(ctx context.Context, req *LeaderReq) (*LeaderResp, error) {
-	data, err := s.search(ctx, s.leaderSchema, req.base)
-	if err != nil {
-		return &LeaderResp{}, err
-	}
-
-	return &LeaderResp{
-		Data: data,
-	}, nil
+	resp := &LeaderResp{}
+	result, err := s.search(ctx, s.leaderSchema, req.base, &resp.Leaders)
+	resp.Result = result
+	return resp, err
 }
 
 type RoleMemberReq struct {
@@ -171,18 +219,15 @@ type RoleMemberReq struct {
 }
 
 type RoleMemberResp struct {
-	Data interface{}
+	Users *Users
+	Result
 }
 
 func (s *Search) RoleMember(ctx context.Context, req *RoleMemberReq) (*RoleMemberResp, error) {
-	data, err := s.search(ctx, s.rolememberSchema, req.base)
-	if err != nil {
-		return &RoleMemberResp{}, err
-	}
-
-	return &RoleMemberResp{
-		Data: data,
-	}, nil
+	resp := &RoleMemberResp{}
+	result, err := s.search(ctx, s.rolememberSchema, req.base, &resp.Users)
+	resp.Result = result
+	return resp, err
 }
 
 type UserByIDsReq struct {
@@ -190,25 +235,46 @@ type UserByIDsReq struct {
 }
 
 type UserByIDsResp struct {
-	Data interface{}
+	Users *Users
+	Result
+}
+
+func (s *Search) UserByIDs(ctx context.Context, req *UserByIDsReq) (*UserByIDsResp, error) {
+	resp := &UserByIDsResp{}
+	result, err := s.search(ctx, s.user.userByIDsSchema, req.base, &resp.Users)
+	resp.Result = result
+	return resp, err
 }
 
-func (s *Search) UserByIDs(ctx context.Context, req *UserByIDsReq) (*UserByIDsResp, interface{}) {
-	data, err := s.search(ctx, s.user.userByIDsSchema, req.base)
+// search execute the query and bind the root field into dst,
+// dst is bound along with errors if part of the query is resolved.
+func (s *Search) search(ctx context.Context, schema graphql.Schema, base base, dst interface{}) (Result, error) {
+	query, err := s.persisted.resolve(base.Query, base.Hash)
 	if err != nil {
-		return &UserByIDsResp{}, err
+		errs := withCode(gqlerrors.FormatError(err))
+		logErrors(ctx, s.log, errs...)
+		return Result{Errors: errs}, err
+	}
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: query,
+	})
+	if err != nil {
+		errs := withCode(gqlerrors.FormatError(err))
+		logErrors(ctx, s.log, errs...)
+		return Result{Errors: errs}, errs[0]
+	}
+	if err := s.limits.analyze(schema, doc); err != nil {
+		errs := withCode(gqlerrors.FormatError(err))
+		logErrors(ctx, s.log, errs...)
+		return Result{Errors: errs}, err
 	}
 
-	return &UserByIDsResp{
-		Data: data,
-	}, nil
-}
-
-func (s *Search) search(ctx context.Context, schema graphql.Schema, base base) (interface{}, error) {
+	ctx, meta := models.WithMeta(ctx)
+	ctx = models.WithTenant(ctx, base.TenantID)
 	params := graphql.Params{
 		Context:       ctx,
 		Schema:        schema,
-		RequestString: base.Query,
+		RequestString: query,
 		RootObject: map[string]interface{}{
 			"userID":       base.UserID,
 			"departmentID": base.DepartmentID,
@@ -216,18 +282,131 @@ func (s *Search) search(ctx context.Context, schema graphql.Schema, base base) (
 		},
 	}
 
-	result := graphql.Do(params)
-	if len(result.Errors) > 0 {
+	ctx, span := tracing.Start(ctx, "graphql.Do",
+		attribute.String("graphql.schema", schema.QueryType().Name()),
+		attribute.String("graphql.operation", operationName(doc)),
+	)
+	params.Context = ctx
+	start := time.Now()
+	data := graphql.Do(params)
+	metrics.ObserveGraphQL(schema.QueryType().Name(), operationKind(base, doc), start, formattedErrors(data.Errors)...)
+	if elapsed := time.Since(start); s.slowThreshold > 0 && elapsed > s.slowThreshold {
+		util.LoggerWithRequest(ctx, s.log).Info("slow query",
+			"schema", schema.QueryType().Name(),
+			"operation", operationName(doc),
+			"query", query,
+			"elapsed", elapsed.String(),
+		)
+	}
+	if len(data.Errors) > 0 {
+		tracing.Error(span, data.Errors[0])
+	}
+	span.End()
+
+	result := Result{
+		Partial: meta.Partial(),
+		Stale:   meta.Stale(),
+	}
+	if len(data.Errors) > 0 {
+		result.Errors = withCode(data.Errors...)
 		logErrors(ctx, s.log, result.Errors...)
-		return nil, result.Errors[0]
+		if !hasData(data.Data) {
+			return result, result.Errors[0]
+		}
+	}
+
+	if err := bindRoot(dst, data.Data); err != nil {
+		util.LoggerWithRequest(ctx, s.log).Error(err, "bind result")
+		return result, err
+	}
+	return result, nil
+}
+
+// operationName return the name of the first operation, which is set by the client,
+// so it goes to the spans and the logs only.
+func operationName(doc *ast.Document) string {
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok && op.Name != nil {
+			return op.Name.Value
+		}
+	}
+	return "anonymous"
+}
+
+// operationKind return the bounded kind of the operation for the metric labels,
+// persisted, named or anonymous.
+func operationKind(base base, doc *ast.Document) string {
+	switch {
+	case base.Hash != "":
+		return "persisted"
+	case operationName(doc) != "anonymous":
+		return "named"
+	}
+	return "anonymous"
+}
+
+func formattedErrors(errs []gqlerrors.FormattedError) []error {
+	result := make([]error, 0, len(errs))
+	for _, err := range errs {
+		result = append(result, err)
+	}
+	return result
+}
+
+// bindRoot bind the only root field of the graphql data into dst,
+// the root field may be aliased, the query of more root fields is rejected by Limits.analyze.
+func bindRoot(dst interface{}, data interface{}) error {
+	root, ok := data.(map[string]interface{})
+	if !ok || len(root) == 0 {
+		return nil
+	}
+
+	for _, value := range root {
+		if value == nil {
+			return nil
+		}
+		body, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(body, dst)
 	}
+	return nil
+}
 
-	return result.Data, nil
+// withCode set the extensions of the errdefs error into the errors without extensions.code.
+func withCode(errs ...gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	for i := range errs {
+		if _, ok := errs[i].Extensions["code"]; ok {
+			continue
+		}
+		if errs[i].Extensions == nil {
+			errs[i].Extensions = make(map[string]interface{}, 1)
+		}
+		for key, value := range errdefs.From(errs[i]).Extensions() {
+			errs[i].Extensions[key] = value
+		}
+	}
+	return errs
+}
+
+func hasData(data interface{}) bool {
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return data != nil
+	}
+	for _, v := range m {
+		if v != nil {
+			return true
+		}
+	}
+	return false
 }
 
 func logErrors(ctx context.Context, log logr.Logger, errors ...gqlerrors.FormattedError) {
+	log = util.LoggerWithRequest(ctx, log)
 	for _, err := range errors {
-		log.Info(err.Message, header.GetRequestIDKV(ctx).Fuzzy()...)
+		log.Info(err.Message)
 	}
 }
 
@@ -0,0 +1,571 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/quanxiang-cloud/search/internal/models/bleve"
+	"github.com/quanxiang-cloud/search/internal/models/memory"
+	"github.com/quanxiang-cloud/search/internal/models/sqldb"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+// newTestSearches return the search services of the storage backends
+// loaded with the same fixtures, keyed by backend.
+func newTestSearches(t *testing.T) map[string]*Search {
+	t.Helper()
+	ctx := context.Background()
+
+	store, err := memory.Load(memory.Config{Fixtures: "testdata/fixtures.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sqldb.Open(ctx, sqldb.Config{
+		Driver:  sqldb.DriverSQLite,
+		DSN:     "file:" + t.Name() + "?mode=memory&cache=shared",
+		Migrate: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	body, err := ioutil.ReadFile("testdata/fixtures.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures := memory.Fixtures{}
+	if err := json.Unmarshal(body, &fixtures); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutDepartments(ctx, fixtures.Departments...); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutUsers(ctx, fixtures.Users...); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := bleve.Open(bleve.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		index.Close()
+	})
+	if err := index.PutDepartments(ctx, fixtures.Departments...); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.PutUsers(ctx, fixtures.Users...); err != nil {
+		t.Fatal(err)
+	}
+
+	searches := make(map[string]*Search)
+	for backend, opt := range map[string]Option{
+		"memory": WithMemory(store),
+		"sqlite": WithSQL(db),
+		"bleve":  WithBleve(index),
+	} {
+		s, err := NewSearch(ctx, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		searches[backend] = s
+	}
+	return searches
+}
+
+func usersIDs(users []User) []string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func userIDs(users *Users) ([]string, int64) {
+	if users == nil || users.Users == nil {
+		return nil, 0
+	}
+	var total int64
+	if users.Total != nil {
+		total = *users.Total
+	}
+	return usersIDs(*users.Users), total
+}
+
+func departmentIDs(deps *Departments) ([]string, int64) {
+	if deps == nil || deps.Departments == nil {
+		return nil, 0
+	}
+	var total int64
+	if deps.Total != nil {
+		total = *deps.Total
+	}
+	ids := make([]string, 0, len(*deps.Departments))
+	for _, dep := range *deps.Departments {
+		ids = append(ids, dep.ID)
+	}
+	return ids, total
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		do    func(s *Search) ([]string, int64, error)
+		ids   []string
+		total int64
+		code  errdefs.Code
+	}{
+		{
+			name: "search user by name prefix in tenant",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchUser(ctx, &SearchUserReq{base{
+					TenantID: "t1",
+					Query:    `{query(name:"ali"){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u1"},
+			total: 1,
+		},
+		{
+			name: "search user by department ordered by createdAt desc",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchUser(ctx, &SearchUserReq{base{
+					TenantID: "t1",
+					Query:    `{query(departmentID:"d1", orderBy:[{createdAt:DESC}]){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u3", "u2", "u1"},
+			total: 3,
+		},
+		{
+			name: "search user paginated by name",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchUser(ctx, &SearchUserReq{base{
+					TenantID: "t1",
+					Query:    `{query(page:2, size:2){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u3", "u4"},
+			total: 4,
+		},
+		{
+			name: "search user by role name and phone prefix",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchUser(ctx, &SearchUserReq{base{
+					TenantID: "t1",
+					Query:    `{query(roleName:"dev", phone:"1380"){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u2"},
+			total: 1,
+		},
+		{
+			name: "search user by use status",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchUser(ctx, &SearchUserReq{base{
+					TenantID: "t1",
+					Query:    `{query(useStatus:-2){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u3"},
+			total: 1,
+		},
+		{
+			name: "search user with invalid query",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchUser(ctx, &SearchUserReq{base{
+					TenantID: "t1",
+					Query:    `{query(name:"ali"){total users{id}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			code: errdefs.InvalidArgument,
+		},
+		{
+			name: "search user with more root fields",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchUser(ctx, &SearchUserReq{base{
+					TenantID: "t1",
+					Query:    `{a:query(name:"ali"){total} ...more} fragment more on _queryUsers{b:query{total}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			code: errdefs.InvalidArgument,
+		},
+		{
+			name: "search department by name prefix",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchDepartment(ctx, &SearchDepartmentReq{base{
+					TenantID: "t1",
+					Query:    `{query(name:"研发"){total departments{id}}}`,
+				}})
+				ids, total := departmentIDs(resp.Departments)
+				return ids, total, err
+			},
+			ids:   []string{"d1", "d2"},
+			total: 2,
+		},
+		{
+			name: "search department by attr ordered by name asc",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.SearchDepartment(ctx, &SearchDepartmentReq{base{
+					TenantID: "t1",
+					Query:    `{query(attr:[2], orderBy:[{name:ASC}]){total departments{id}}}`,
+				}})
+				ids, total := departmentIDs(resp.Departments)
+				return ids, total, err
+			},
+			ids:   []string{"d3", "d2"},
+			total: 2,
+		},
+		{
+			name: "departments by ids",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.DepartmentByIDs(ctx, &DepartmentsByIDsReq{base{
+					TenantID: "t1",
+					Query:    `{query(ids:["d3","d1"]){total departments{id}}}`,
+				}})
+				ids, total := departmentIDs(resp.Departments)
+				return ids, total, err
+			},
+			ids:   []string{"d1", "d3"},
+			total: 2,
+		},
+		{
+			name: "department member",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.DepartmentMember(ctx, &DepartmentMemberReq{base{
+					TenantID: "t1",
+					Query:    `{query(departmentID:"d2"){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u2", "u3"},
+			total: 2,
+		},
+		{
+			name: "department member without department",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.DepartmentMember(ctx, &DepartmentMemberReq{base{
+					TenantID: "t1",
+					Query:    `{query(departmentID:""){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			code: errdefs.InvalidArgument,
+		},
+		{
+			name: "subordinate",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.Subordinate(ctx, &SubordinateReq{base{
+					TenantID: "t1",
+					UserID:   "u1",
+					Query:    `{query{total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u2", "u3", "u4"},
+			total: 3,
+		},
+		{
+			name: "leader",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.Leader(ctx, &LeaderReq{base{
+					TenantID: "t1",
+					UserID:   "u3",
+					Query:    `{query{id name}}`,
+				}})
+				ids := usersIDs(resp.Leaders)
+				return ids, int64(len(ids)), err
+			},
+			ids:   []string{"u1", "u2"},
+			total: 2,
+		},
+		{
+			name: "leader of unknown user",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.Leader(ctx, &LeaderReq{base{
+					TenantID: "t1",
+					UserID:   "unknown",
+					Query:    `{query{id}}`,
+				}})
+				ids := usersIDs(resp.Leaders)
+				return ids, int64(len(ids)), err
+			},
+			code: errdefs.NotFound,
+		},
+		{
+			name: "role member",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.RoleMember(ctx, &RoleMemberReq{base{
+					TenantID: "t1",
+					Query:    `{query(roleID:"r1"){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u1", "u4"},
+			total: 2,
+		},
+		{
+			name: "users by ids",
+			do: func(s *Search) ([]string, int64, error) {
+				resp, err := s.UserByIDs(ctx, &UserByIDsReq{base{
+					TenantID: "t1",
+					Query:    `{query(ids:["u4","u2"]){total users{id}}}`,
+				}})
+				ids, total := userIDs(resp.Users)
+				return ids, total, err
+			},
+			ids:   []string{"u2", "u4"},
+			total: 2,
+		},
+	}
+
+	for backend, s := range newTestSearches(t) {
+		for _, tt := range tests {
+			t.Run(backend+"/"+tt.name, func(t *testing.T) {
+				ids, total, err := tt.do(s)
+				if tt.code != errdefs.Success {
+					if err == nil || errdefs.From(err).Code != tt.code {
+						t.Fatalf("want code %s, got %v", tt.code, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(ids, tt.ids) {
+					t.Errorf("want ids %v, got %v", tt.ids, ids)
+				}
+				if total != tt.total {
+					t.Errorf("want total %d, got %d", tt.total, total)
+				}
+			})
+		}
+	}
+}
+
+func TestOperationKind(t *testing.T) {
+	tests := []struct {
+		base  base
+		query string
+		kind  string
+	}{
+		{base{}, `{query{total}}`, "anonymous"},
+		{base{}, `query Q1f9a{query{total}}`, "named"},
+		{base{Hash: "abc"}, `query Q{query{total}}`, "persisted"},
+	}
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind := operationKind(tt.base, doc); kind != tt.kind {
+			t.Errorf("%s: want kind %s, got %s", tt.query, tt.kind, kind)
+		}
+	}
+}
+
+func TestPersistedQueryErrorCode(t *testing.T) {
+	store, err := memory.Load(memory.Config{Fixtures: "testdata/fixtures.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := `{query{total}}`
+	tests := []struct {
+		name    string
+		enforce bool
+		req     base
+		code    string
+	}{
+		{"unknown hash", false, base{Hash: QueryHash("unknown")}, persistedQueryNotFound},
+		{"hash mismatch", false, base{Query: query, Hash: QueryHash("other")}, persistedQueryHashMismatch},
+		{"query not allowed", true, base{Query: query}, persistedQueryNotSupported},
+	}
+	for _, tt := range tests {
+		s, err := NewSearch(context.Background(), WithMemory(store), WithPersistedQuery(PersistedQuery{Enforce: tt.enforce}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &SearchUserReq{}
+		req.base = tt.req
+		req.TenantID = "t1"
+		resp, err := s.SearchUser(context.Background(), req)
+		if errdefs.From(err).Code != errdefs.InvalidArgument {
+			t.Errorf("%s: want invalid argument, got %v", tt.name, err)
+			continue
+		}
+		if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != tt.code {
+			t.Errorf("%s: want extensions.code %s, got %v", tt.name, tt.code, resp.Errors)
+		}
+	}
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	dir := t.TempDir()
+	// the trailing newline is part of the text sent by the clients
+	query := "query Users {\n  query(page: 1, size: 1) {\n    total\n  }\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "users.graphql"), []byte(query), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("{query{total}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	queries, err := LoadPersistedQueries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 1 || queries[QueryHash(query)] != query {
+		t.Fatalf("queries: %v", queries)
+	}
+
+	store, err := memory.Load(memory.Config{Fixtures: "testdata/fixtures.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSearch(context.Background(), WithMemory(store),
+		WithPersistedQuery(PersistedQuery{Queries: queries, Enforce: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &SearchUserReq{}
+	req.TenantID, req.Hash = "t1", QueryHash(query)
+	resp, err := s.SearchUser(context.Background(), req)
+	if err != nil {
+		t.Fatalf("hash of the file: %v", err)
+	}
+	if resp.Users == nil || resp.Users.Total == nil || *resp.Users.Total != 4 {
+		t.Errorf("hash of the file: %+v", resp.Users)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s, err := NewSearch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const allFields = `id name phone email createdAt jobNumber avatar useStatus tenantID gender source selfEmail position
+		departments{id name attr} roles{id name pid} leaders{id name attr}`
+
+	tests := []struct {
+		name   string
+		limits Limits
+		schema graphql.Schema
+		query  string
+		err    string
+	}{{
+		name:   "default page",
+		schema: s.user.querySchema,
+		// query 1 + 10 documents + total, users, id and name
+		query: `{query{total users{id name}}}`,
+	}, {
+		name:   "max page with all fields",
+		schema: s.user.querySchema,
+		query:  `{query(size:999){total users{` + allFields + `}}}`,
+	}, {
+		name:   "zero size means the max page",
+		limits: Limits{MaxComplexity: 1004},
+		schema: s.user.querySchema,
+		query:  `{query(size:0){total users{id name}}}`,
+	}, {
+		name:   "over complexity by size",
+		limits: Limits{MaxComplexity: 1003},
+		schema: s.user.querySchema,
+		query:  `{query(size:999){total users{id name}}}`,
+		err:    "query complexity 1004 exceeds the max complexity 1003",
+	}, {
+		name:   "over complexity by ids",
+		limits: Limits{MaxComplexity: 5},
+		schema: s.user.userByIDsSchema,
+		query:  `{query(ids:["u1","u2","u3"]){users{id}}}`,
+		err:    "query complexity 6 exceeds the max complexity 5",
+	}, {
+		name:   "within complexity by ids",
+		limits: Limits{MaxComplexity: 6},
+		schema: s.user.userByIDsSchema,
+		query:  `{query(ids:["u1","u2","u3"]){users{id}}}`,
+	}, {
+		name:   "cost of the leaders",
+		limits: Limits{MaxComplexity: 3},
+		schema: s.user.leaderSchema,
+		// whoami and the leaders cost 2
+		query: `{query{id name}}`,
+		err:   "query complexity 4 exceeds the max complexity 3",
+	}, {
+		name:   "over depth",
+		limits: Limits{MaxDepth: 3},
+		schema: s.user.querySchema,
+		query:  `{query{users{departments{id}}}}`,
+		err:    "query depth 4 exceeds the max depth 3",
+	}, {
+		name:   "within depth",
+		limits: Limits{MaxDepth: 3},
+		schema: s.user.querySchema,
+		query:  `{query{users{id}}}`,
+	}, {
+		name:   "depth of the fragments",
+		limits: Limits{MaxDepth: 3},
+		schema: s.user.querySchema,
+		query:  `{query{...q}} fragment q on users{users{...u}} fragment u on user{departments{id}}`,
+		err:    "query depth 4 exceeds the max depth 3",
+	}, {
+		name:   "complexity of the inline fragments",
+		limits: Limits{MaxComplexity: 13},
+		schema: s.department.querySchema,
+		query:  `{query{... on departments{total departments{id}}}}`,
+		err:    "query complexity 14 exceeds the max complexity 13",
+	}, {
+		name:   "cyclic fragments",
+		schema: s.user.querySchema,
+		query:  `{query{...a}} fragment a on users{users{id} ...b} fragment b on users{total ...a}`,
+	}, {
+		name:   "more root fields by fragments",
+		schema: s.user.querySchema,
+		query:  `{query{total} ...f} fragment f on _queryUsers{query{total}}`,
+		err:    "only one root field is allowed, got 2",
+	}}
+	for _, tt := range tests {
+		doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		err = tt.limits.withDefault().analyze(tt.schema, doc)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: error %v, expect %q", tt.name, err, tt.err)
+		case err != nil && errdefs.From(err).Code != errdefs.InvalidArgument:
+			t.Errorf("%s: code %d, expect invalid argument", tt.name, errdefs.From(err).Code)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+
+	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/tracing"
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/util"
+)
+
+// UserPage users of the typed query.
+type UserPage struct {
+	Users []*v1alpha1.User
+	// Total total of the query, the number of users if not paged.
+	Total int64
+	Result
+}
+
+// DepartmentPage departments of the typed query.
+type DepartmentPage struct {
+	Departments []*v1alpha1.Department
+	// Total total of the query, the number of departments if not paged.
+	Total int64
+	Result
+}
+
+// QueryUsers search the users by the typed query, the counterpart of SearchUser
+// without graphql. Department members, subordinates and role members are
+// the queries with DepartmentID, LeaderID and RoleID.
+// page starts from 1, size is the max size if zero.
+func (s *Search) QueryUsers(ctx context.Context, query *v1alpha1.SearchUser, page, size int) (*UserPage, error) {
+	page, size, err := typedPage(page, size)
+	if err != nil {
+		return &UserPage{}, err
+	}
+	resp := &UserPage{}
+	resp.Result, err = s.typed(ctx, "QueryUsers", query.TenantID, func(ctx context.Context) (err error) {
+		resp.Users, resp.Total, err = s.user.userRepo.Search(ctx, query, page, size)
+		return err
+	})
+	return resp, err
+}
+
+// QueryUsersByIDs list the users of ids, the counterpart of UserByIDs.
+func (s *Search) QueryUsersByIDs(ctx context.Context, tenantID string, ids []string) (*UserPage, error) {
+	resp := &UserPage{}
+	var err error
+	resp.Result, err = s.typed(ctx, "QueryUsersByIDs", tenantID, func(ctx context.Context) (err error) {
+		resp.Users, err = s.user.userRepo.List(ctx, interfaces(ids))
+		resp.Total = int64(len(resp.Users))
+		return err
+	})
+	return resp, err
+}
+
+// QueryLeaders list the leaders of the user, from the recent leader to the top leader,
+// the counterpart of Leader.
+func (s *Search) QueryLeaders(ctx context.Context, tenantID, userID string) (*UserPage, error) {
+	resp := &UserPage{}
+	var err error
+	resp.Result, err = s.typed(ctx, "QueryLeaders", tenantID, func(ctx context.Context) (err error) {
+		resp.Users, err = s.user.leaders(ctx, userID)
+		resp.Total = int64(len(resp.Users))
+		return err
+	})
+	return resp, err
+}
+
+// QueryDepartments search the departments by the typed query, the counterpart of SearchDepartment.
+// page starts from 1, size is the max size if zero.
+func (s *Search) QueryDepartments(ctx context.Context, query *v1alpha1.SearchDepartment, page, size int) (*DepartmentPage, error) {
+	page, size, err := typedPage(page, size)
+	if err != nil {
+		return &DepartmentPage{}, err
+	}
+	resp := &DepartmentPage{}
+	resp.Result, err = s.typed(ctx, "QueryDepartments", query.TenantID, func(ctx context.Context) (err error) {
+		resp.Departments, resp.Total, err = s.department.depRepo.Search(ctx, query, page, size)
+		return err
+	})
+	return resp, err
+}
+
+// typed call fn with the tenant and the meta of the repos like search,
+// the result carries the partial and stale flags collected by the repos.
+func (s *Search) typed(ctx context.Context, name, tenantID string, fn func(ctx context.Context) error) (Result, error) {
+	ctx, meta := models.WithMeta(ctx)
+	ctx = models.WithTenant(ctx, tenantID)
+	ctx, span := tracing.Start(ctx, "service."+name)
+	defer span.End()
+
+	err := fn(ctx)
+	if err != nil {
+		tracing.Error(span, err)
+		util.LoggerWithRequest(ctx, s.log).Info(err.Error(), "query", name)
+		return Result{}, err
+	}
+	return Result{
+		Partial: meta.Partial(),
+		Stale:   meta.Stale(),
+	}, nil
+}
+
+// typedPage return the page from 1 and the size of the typed queries.
+func typedPage(page, size int) (int, int, error) {
+	if size < 0 || size > maxSize {
+		return 0, 0, errdefs.NewInvalidArgument("size must be between 0 and %d, got %d", maxSize, size)
+	}
+	if size == 0 {
+		size = maxSize
+	}
+	if page < 1 {
+		page = 1
+	}
+	return page, size, nil
+}
+
+func interfaces(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
@@ -1,12 +1,15 @@
 package service
 
 import (
-	"errors"
-	"fmt"
+	"context"
+
 	"github.com/go-logr/logr"
 	"github.com/graphql-go/graphql"
 	"github.com/quanxiang-cloud/search/internal/models"
+	"github.com/quanxiang-cloud/search/internal/tracing"
 	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+	"github.com/quanxiang-cloud/search/pkg/util"
 )
 
 var depInfo = graphql.NewObject(
@@ -181,7 +184,7 @@ func (u *user) resolve(p graphql.ResolveParams) (interface{}, error) {
 	}
 	err := mapToStruct(query, p.Args)
 	if err != nil {
-		u.log.Error(err, "bind args")
+		util.LoggerWithRequest(p.Context, u.log).Error(err, "bind args")
 		return nil, err
 	}
 	page, size := bindPageSize(p.Args)
@@ -190,7 +193,7 @@ func (u *user) resolve(p graphql.ResolveParams) (interface{}, error) {
 		page, size,
 	)
 	if err != nil {
-		u.log.Error(err, "search user")
+		util.LoggerWithRequest(p.Context, u.log).Error(err, "search user")
 		return nil, err
 	}
 
@@ -243,7 +246,7 @@ func (u *user) query() error {
 						},
 					},
 					),
-					Resolve: u.resolve,
+					Resolve: tracing.Resolve("user.resolve", u.resolve),
 				},
 			},
 		}),
@@ -270,7 +273,7 @@ func (u *user) getByIDs() error {
 							Type: graphql.NewList(graphql.String),
 						},
 					},
-					Resolve: u.getByIDsResolve,
+					Resolve: tracing.Resolve("user.getByIDsResolve", u.getByIDsResolve),
 				},
 			},
 		}),
@@ -286,11 +289,11 @@ func (u *user) getByIDs() error {
 func (u *user) getByIDsResolve(p graphql.ResolveParams) (interface{}, error) {
 	ids, ok := p.Args["ids"].([]interface{})
 	if !ok {
-		return nil, errors.New("invalid id type")
+		return nil, errdefs.NewInvalidArgument("invalid id type")
 	}
 	list, err := u.userRepo.List(p.Context, ids)
 	if err != nil {
-		u.log.Error(err, "search user")
+		util.LoggerWithRequest(p.Context, u.log).Error(err, "search user")
 		return nil, err
 	}
 	return struct {
@@ -327,13 +330,13 @@ func (u *user) departmentMember() error {
 						},
 					},
 					),
-					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					Resolve: tracing.Resolve("_departmentMember", func(p graphql.ResolveParams) (interface{}, error) {
 						if p.Args["departmentID"] == "" {
-							return nil, fmt.Errorf("department id is must")
+							return nil, errdefs.NewInvalidArgument("department id is must")
 						}
 
 						return u.resolve(p)
-					},
+					}),
 				},
 			},
 		}),
@@ -372,10 +375,10 @@ func (u *user) subordinate() error {
 						},
 					},
 					),
-					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					Resolve: tracing.Resolve("_subordinate", func(p graphql.ResolveParams) (interface{}, error) {
 						p.Args["leaderID"] = p.Source.(map[string]interface{})["userID"]
 						return u.resolve(p)
-					},
+					}),
 				},
 			},
 		}),
@@ -396,29 +399,14 @@ func (u *user) leader() error {
 			Fields: graphql.Fields{
 				"query": &graphql.Field{
 					Type: graphql.NewList(UserInfo),
-					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-						ctx := p.Context
+					Resolve: tracing.Resolve("_leader", func(p graphql.ResolveParams) (interface{}, error) {
 						userID := p.Source.(map[string]interface{})["userID"]
-						whoami, err := u.userRepo.Get(ctx, userID.(string))
+						leaders, err := u.leaders(p.Context, userID.(string))
 						if err != nil {
 							return nil, err
 						}
-						// very serious error, once here is nil,
-						// it means the data is inconsistent
-						if whoami == nil {
-							return nil, fmt.Errorf("user not exist")
-						}
-
-						leaderIDs := make([]interface{}, 0, len(whoami.Leaders))
-						for _, leader := range whoami.Leaders {
-							for _, l := range leader {
-								leaderIDs = append(leaderIDs, l.ID)
-							}
-
-						}
-
-						return u.userRepo.List(ctx, leaderIDs)
-					},
+						return leaders, nil
+					}),
 				},
 			},
 		}),
@@ -432,6 +420,29 @@ func (u *user) leader() error {
 	return nil
 }
 
+// leaders return the leaders of the user, from the recent leader to the top leader.
+func (u *user) leaders(ctx context.Context, userID string) ([]*v1alpha1.User, error) {
+	whoami, err := u.userRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// very serious error, once here is nil,
+	// it means the data is inconsistent
+	if whoami == nil {
+		return nil, errdefs.NewNotFound("user not exist")
+	}
+
+	leaderIDs := make([]interface{}, 0, len(whoami.Leaders))
+	for _, leader := range whoami.Leaders {
+		for _, l := range leader {
+			leaderIDs = append(leaderIDs, l.ID)
+		}
+
+	}
+
+	return u.userRepo.List(ctx, leaderIDs)
+}
+
 func (u *user) roleMember() error {
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{
 		Query: graphql.NewObject(graphql.ObjectConfig{
@@ -457,9 +468,9 @@ func (u *user) roleMember() error {
 						},
 					},
 					),
-					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					Resolve: tracing.Resolve("_roleMember", func(p graphql.ResolveParams) (interface{}, error) {
 						return u.resolve(p)
-					},
+					}),
 				},
 			},
 		}),
@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	instrumentationName = "github.com/quanxiang-cloud/search"
+
+	serviceName = "search"
+)
+
+// exporters
+const (
+	// ExporterNone spans are not recorded, trace context is still propagated.
+	ExporterNone = ""
+	// ExporterOTLP export spans by OTLP over http.
+	ExporterOTLP = "otlp"
+)
+
+// Config tracing config
+type Config struct {
+	Exporter string `yaml:"exporter"`
+	// Endpoint host:port of the OTLP http receiver
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+	// SampleRatio ratio of the root spans to be sampled, 0 means 1
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// Init set the global tracer provider and the W3C trace context propagator,
+// the returned function flushes and stops the exporter.
+func Init(ctx context.Context, conf Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	switch conf.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", conf.Exporter)
+	}
+
+	opts := []otlptracehttp.Option{}
+	if conf.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpoint(conf.Endpoint))
+	}
+	if conf.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := conf.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(serviceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start start a span from the global tracer.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Error record the error on the span.
+func Error(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Resolve wrap the resolver with a span.
+func Resolve(name string, fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx, span := Start(p.Context, name)
+		defer span.End()
+
+		p.Context = ctx
+		result, err := fn(p)
+		Error(span, err)
+		return result, err
+	}
+}
+
+// Middleware start a server span for every request,
+// the trace context is extracted from the incoming headers.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serviceName, route, c.Request)...),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(status))
+	}
+}
+
+// UnaryServerInterceptor start a server span for every grpc request like Middleware,
+// the trace context is extracted from the incoming metadata.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+		attrs := []attribute.KeyValue{semconv.RPCSystemKey.String("grpc")}
+		// full method is formed by /package.service/method
+		name := strings.TrimPrefix(info.FullMethod, "/")
+		if i := strings.LastIndex(name, "/"); i > 0 {
+			attrs = append(attrs, semconv.RPCServiceKey.String(name[:i]), semconv.RPCMethodKey.String(name[i+1:]))
+		}
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int64(int64(code)))
+		if err != nil {
+			span.SetStatus(codes.Error, code.String())
+		}
+		return resp, err
+	}
+}
+
+// metadataCarrier adapt the grpc metadata to the propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -2,19 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-logr/zapr"
+	cabinlogger "github.com/quanxiang-cloud/cabin/logger"
 	"github.com/quanxiang-cloud/search/api"
 	"github.com/quanxiang-cloud/search/internal/config"
+	"github.com/quanxiang-cloud/search/internal/tracing"
 	"github.com/quanxiang-cloud/search/pkg/util"
 	"go.uber.org/zap"
 )
 
 func main() {
-	var configFile string
-	flag.StringVar(&configFile, "config", "./config.yaml", "config path")
+	var (
+		configFile string
+		overrides  config.Overrides
+	)
+	flag.StringVar(&configFile, "config", "./config.yaml", "config path, empty for defaults and environment variables only")
+	flag.Var(&overrides, "set", "override the config by path=value, e.g. -set timeout.default=5s, repeatable")
 	flag.Parse()
 
 	zapLog, err := zap.NewDevelopment()
@@ -26,7 +38,23 @@ func main() {
 	ctx := context.Background()
 	ctx = util.SetCtx(ctx, util.ContextKey{}, logger)
 
-	conf, err := config.New(ctx, configFile)
+	conf, err := config.New(ctx, configFile, overrides...)
+	if err != nil {
+		panic(err)
+	}
+
+	// replace the bootstrap logger with the configured one.
+	_ = zapLog.Sync()
+	zapLog, err = util.NewLogger(conf.Log)
+	if err != nil {
+		panic(err)
+	}
+	logger = zapr.NewLogger(zapLog)
+	ctx = util.SetCtx(ctx, util.ContextKey{}, logger)
+	util.SetDefaultLogger(logger)
+	cabinlogger.Logger = cabinlogger.NewFromLogr(logger)
+
+	shutdownTracing, err := tracing.Init(ctx, conf.Tracing)
 	if err != nil {
 		panic(err)
 	}
@@ -37,6 +65,69 @@ func main() {
 	}
 	router.Probe.SetRunning()
 
-	logger.Info("running...")
-	router.Run(conf.Port)
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	if configFile != "" {
+		err := config.Watch(watchCtx, configFile, overrides, func(conf *config.Config) error {
+			return router.Reload(watchCtx, conf)
+		})
+		if err != nil {
+			logger.Error(err, "watch config")
+		}
+	}
+
+	servers := 1
+	errCh := make(chan error, 2)
+	go func() {
+		logger.Info("running...")
+		errCh <- router.Run(conf.Port)
+	}()
+	if conf.GRPC.Port != "" {
+		servers++
+		go func() {
+			logger.Info("grpc running...", "port", conf.GRPC.Port)
+			errCh <- router.RunGRPC(conf.GRPC.Port)
+		}()
+	}
+
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGTERM, syscall.SIGINT)
+
+	running := true
+	select {
+	case sig := <-signalCh:
+		logger.Info("receive signal", "signal", sig.String())
+	case <-router.Probe.Shutdown():
+		logger.Info("shutdown by readiness probe")
+	case err := <-errCh:
+		logger.Error(err, "router run")
+		running = false
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, conf.Shutdown.Drain+conf.Shutdown.Timeout)
+	defer cancel()
+	if running {
+		// fail the readiness probe first, wait for the load balancer
+		// to remove the instance, and then drain the in-flight requests.
+		router.Probe.SetDraining()
+		time.Sleep(conf.Shutdown.Drain)
+
+		if err := router.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "router shutdown")
+		}
+		for i := 0; i < servers; i++ {
+			if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error(err, "router run")
+			}
+		}
+	}
+
+	stopWatch()
+	router.Close()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error(err, "tracing shutdown")
+	}
+
+	logger.Info("shutdown")
+	_ = zapLog.Sync()
 }
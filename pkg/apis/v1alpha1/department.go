@@ -8,12 +8,14 @@ type Department struct {
 	TenantID string `json:"tenantID"`
 }
 
+// SearchDepartment filters of the departments, the form tags bind the query parameters
+// of the rest api, the tenant comes from the header.
 type SearchDepartment struct {
-	TenantID string        `json:"tenantID,omitempty"`
-	Name     string        `json:"name,omitempty"`
-	Attr     []int         `json:"attr,omitempty"`
-	IDS      []interface{} `json:"ids,omitempty"`
-	OrderBy  []string      `json:"orderBy,omitempty"`
+	TenantID string        `json:"tenantID,omitempty" form:"-"`
+	Name     string        `json:"name,omitempty" form:"name"`
+	Attr     []int         `json:"attr,omitempty" form:"attr"`
+	IDS      []interface{} `json:"ids,omitempty" form:"-"`
+	OrderBy  []string      `json:"orderBy,omitempty" form:"orderBy"`
 }
 
 const DepartmentIndex = "department"
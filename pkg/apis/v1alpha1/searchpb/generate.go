@@ -0,0 +1,4 @@
+// Package searchpb protobuf messages and grpc service of the search api.
+package searchpb
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative searchpb/search.proto
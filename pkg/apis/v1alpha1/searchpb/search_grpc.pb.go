@@ -0,0 +1,321 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: searchpb/search.proto
+
+package searchpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// SearchClient is the client API for Search service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SearchClient interface {
+	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	GetUsersByIDs(ctx context.Context, in *GetUsersByIDsRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	DepartmentMembers(ctx context.Context, in *DepartmentMembersRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	Subordinates(ctx context.Context, in *SubordinatesRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	Leaders(ctx context.Context, in *LeadersRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	RoleMembers(ctx context.Context, in *RoleMembersRequest, opts ...grpc.CallOption) (*UsersResponse, error)
+	SearchDepartments(ctx context.Context, in *SearchDepartmentsRequest, opts ...grpc.CallOption) (*DepartmentsResponse, error)
+}
+
+type searchClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSearchClient(cc grpc.ClientConnInterface) SearchClient {
+	return &searchClient{cc}
+}
+
+func (c *searchClient) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := c.cc.Invoke(ctx, "/search.v1alpha1.Search/SearchUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) GetUsersByIDs(ctx context.Context, in *GetUsersByIDsRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := c.cc.Invoke(ctx, "/search.v1alpha1.Search/GetUsersByIDs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) DepartmentMembers(ctx context.Context, in *DepartmentMembersRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := c.cc.Invoke(ctx, "/search.v1alpha1.Search/DepartmentMembers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) Subordinates(ctx context.Context, in *SubordinatesRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := c.cc.Invoke(ctx, "/search.v1alpha1.Search/Subordinates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) Leaders(ctx context.Context, in *LeadersRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := c.cc.Invoke(ctx, "/search.v1alpha1.Search/Leaders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) RoleMembers(ctx context.Context, in *RoleMembersRequest, opts ...grpc.CallOption) (*UsersResponse, error) {
+	out := new(UsersResponse)
+	err := c.cc.Invoke(ctx, "/search.v1alpha1.Search/RoleMembers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *searchClient) SearchDepartments(ctx context.Context, in *SearchDepartmentsRequest, opts ...grpc.CallOption) (*DepartmentsResponse, error) {
+	out := new(DepartmentsResponse)
+	err := c.cc.Invoke(ctx, "/search.v1alpha1.Search/SearchDepartments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SearchServer is the server API for Search service.
+// All implementations must embed UnimplementedSearchServer
+// for forward compatibility
+type SearchServer interface {
+	SearchUsers(context.Context, *SearchUsersRequest) (*UsersResponse, error)
+	GetUsersByIDs(context.Context, *GetUsersByIDsRequest) (*UsersResponse, error)
+	DepartmentMembers(context.Context, *DepartmentMembersRequest) (*UsersResponse, error)
+	Subordinates(context.Context, *SubordinatesRequest) (*UsersResponse, error)
+	Leaders(context.Context, *LeadersRequest) (*UsersResponse, error)
+	RoleMembers(context.Context, *RoleMembersRequest) (*UsersResponse, error)
+	SearchDepartments(context.Context, *SearchDepartmentsRequest) (*DepartmentsResponse, error)
+	mustEmbedUnimplementedSearchServer()
+}
+
+// UnimplementedSearchServer must be embedded to have forward compatible implementations.
+type UnimplementedSearchServer struct {
+}
+
+func (UnimplementedSearchServer) SearchUsers(context.Context, *SearchUsersRequest) (*UsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchUsers not implemented")
+}
+func (UnimplementedSearchServer) GetUsersByIDs(context.Context, *GetUsersByIDsRequest) (*UsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsersByIDs not implemented")
+}
+func (UnimplementedSearchServer) DepartmentMembers(context.Context, *DepartmentMembersRequest) (*UsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DepartmentMembers not implemented")
+}
+func (UnimplementedSearchServer) Subordinates(context.Context, *SubordinatesRequest) (*UsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Subordinates not implemented")
+}
+func (UnimplementedSearchServer) Leaders(context.Context, *LeadersRequest) (*UsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Leaders not implemented")
+}
+func (UnimplementedSearchServer) RoleMembers(context.Context, *RoleMembersRequest) (*UsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RoleMembers not implemented")
+}
+func (UnimplementedSearchServer) SearchDepartments(context.Context, *SearchDepartmentsRequest) (*DepartmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchDepartments not implemented")
+}
+func (UnimplementedSearchServer) mustEmbedUnimplementedSearchServer() {}
+
+// UnsafeSearchServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SearchServer will
+// result in compilation errors.
+type UnsafeSearchServer interface {
+	mustEmbedUnimplementedSearchServer()
+}
+
+func RegisterSearchServer(s grpc.ServiceRegistrar, srv SearchServer) {
+	s.RegisterService(&Search_ServiceDesc, srv)
+}
+
+func _Search_SearchUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).SearchUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.v1alpha1.Search/SearchUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).SearchUsers(ctx, req.(*SearchUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_GetUsersByIDs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsersByIDsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).GetUsersByIDs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.v1alpha1.Search/GetUsersByIDs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).GetUsersByIDs(ctx, req.(*GetUsersByIDsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_DepartmentMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DepartmentMembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).DepartmentMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.v1alpha1.Search/DepartmentMembers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).DepartmentMembers(ctx, req.(*DepartmentMembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_Subordinates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubordinatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Subordinates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.v1alpha1.Search/Subordinates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Subordinates(ctx, req.(*SubordinatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_Leaders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeadersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).Leaders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.v1alpha1.Search/Leaders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).Leaders(ctx, req.(*LeadersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_RoleMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleMembersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).RoleMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.v1alpha1.Search/RoleMembers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).RoleMembers(ctx, req.(*RoleMembersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Search_SearchDepartments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchDepartmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SearchServer).SearchDepartments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/search.v1alpha1.Search/SearchDepartments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SearchServer).SearchDepartments(ctx, req.(*SearchDepartmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Search_ServiceDesc is the grpc.ServiceDesc for Search service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Search_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "search.v1alpha1.Search",
+	HandlerType: (*SearchServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SearchUsers",
+			Handler:    _Search_SearchUsers_Handler,
+		},
+		{
+			MethodName: "GetUsersByIDs",
+			Handler:    _Search_GetUsersByIDs_Handler,
+		},
+		{
+			MethodName: "DepartmentMembers",
+			Handler:    _Search_DepartmentMembers_Handler,
+		},
+		{
+			MethodName: "Subordinates",
+			Handler:    _Search_Subordinates_Handler,
+		},
+		{
+			MethodName: "Leaders",
+			Handler:    _Search_Leaders_Handler,
+		},
+		{
+			MethodName: "RoleMembers",
+			Handler:    _Search_RoleMembers_Handler,
+		},
+		{
+			MethodName: "SearchDepartments",
+			Handler:    _Search_SearchDepartments_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "searchpb/search.proto",
+}
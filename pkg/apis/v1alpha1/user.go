@@ -44,26 +44,28 @@ type Role struct {
 	Name string `json:"name,omitempty"`
 }
 
+// SearchUser filters of the users, the form tags bind the query parameters
+// of the rest api, the tenant comes from the header.
 type SearchUser struct {
-	TenantID string `json:"tenantID,omitempty"`
+	TenantID string `json:"tenantID,omitempty" form:"-"`
 
-	Name      string `json:"name,omitempty"`
-	Phone     string `json:"phone,omitempty"`
-	Email     string `json:"email,omitempty"`
-	JobNumber string `json:"tobNumber,omitempty"`
-	Gender    string `json:"gender,omitempty"`
-	UseStatus int    `json:"useStatus,omitempty"`
+	Name      string `json:"name,omitempty" form:"name"`
+	Phone     string `json:"phone,omitempty" form:"phone"`
+	Email     string `json:"email,omitempty" form:"email"`
+	JobNumber string `json:"tobNumber,omitempty" form:"jobNumber"`
+	Gender    string `json:"gender,omitempty" form:"gender"`
+	UseStatus int    `json:"useStatus,omitempty" form:"useStatus"`
 
-	DepartmentName string `json:"departmentName,omitempty"`
-	DepartmentID   string `json:"departmentID,omitempty"`
+	DepartmentName string `json:"departmentName,omitempty" form:"departmentName"`
+	DepartmentID   string `json:"departmentID,omitempty" form:"departmentID"`
 
-	RoleID   string `json:"roleID,omitempty"`
-	RoleName string `json:"roleName,omitempty"`
+	RoleID   string `json:"roleID,omitempty" form:"roleID"`
+	RoleName string `json:"roleName,omitempty" form:"roleName"`
 
-	LeaderID string `json:"leaderID,omitempty"`
+	LeaderID string `json:"leaderID,omitempty" form:"leaderID"`
 
-	OrderBy  []string `json:"orderBy,omitempty"`
-	Position string   `json:"position,omitempty"`
+	OrderBy  []string `json:"orderBy,omitempty" form:"orderBy"`
+	Position string   `json:"position,omitempty" form:"position"`
 }
 
 const UserIndex = "user"
@@ -0,0 +1,279 @@
+// Package client typed client of the search api.
+//
+//	c := client.New("http://search", client.WithTenant("t1"))
+//	page, err := c.SearchUsers(ctx, client.NewUserQuery().Name("zhang").OrderBy("-createdAt"), 1, 20)
+//
+// The tenant and the user set by the options are sent as the Tenant-Id and User-Id headers,
+// which are overridden per request by ContextWithTenant and ContextWithUser.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+// BasePath path prefix of the search endpoints.
+const BasePath = "/api/v1/search"
+
+// headers of the requests
+const (
+	HeaderTenantID  = "Tenant-Id"
+	HeaderUserID    = "User-Id"
+	HeaderRequestID = "Request-Id"
+)
+
+// Client client of the search api, safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	tenantID   string
+	userID     string
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// Option option
+type Option func(*Client)
+
+// WithHTTPClient send the requests by httpClient, default http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTenant set the default tenant of the requests.
+func WithTenant(tenantID string) Option {
+	return func(c *Client) {
+		c.tenantID = tenantID
+	}
+}
+
+// WithUser set the default user of the requests.
+func WithUser(userID string) Option {
+	return func(c *Client) {
+		c.userID = userID
+	}
+}
+
+// WithRetries retry the requests failed by the network or the unavailable server
+// at most retries times, the delay starts from backoff, doubled on each retry
+// and fully jittered. Default 2 retries from 100ms, zero retries means never.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		if backoff > 0 {
+			c.backoff = backoff
+		}
+	}
+}
+
+// New return the client of the search service at baseURL, e.g. http://search.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retries:    2,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type contextKey int
+
+const (
+	tenantKey contextKey = iota
+	userKey
+	requestIDKey
+)
+
+// ContextWithTenant return ctx carrying the tenant of the requests,
+// which overrides the tenant of the client.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// ContextWithUser return ctx carrying the user of the requests,
+// which overrides the user of the client.
+func ContextWithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey, userID)
+}
+
+// ContextWithRequestID return ctx carrying the request id of the requests.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// Error error returned by the server, errors.As with *errdefs.Error works on it.
+type Error struct {
+	// StatusCode http status of the response.
+	StatusCode int
+	Code       errdefs.Code
+	Message    string
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("search: %s (%d): %s", e.Code, e.StatusCode, e.Message)
+}
+
+// Unwrap return the *errdefs.Error of the code.
+func (e *Error) Unwrap() error {
+	return errdefs.New(e.Code, "%s", e.Message)
+}
+
+// Meta metadata of the response.
+type Meta struct {
+	RequestID string
+	// Partial true if the data is incomplete.
+	Partial bool
+	// Stale true if the data is the last known good one served while the storage is down.
+	Stale bool
+}
+
+// envelope response envelope of the search endpoints.
+type envelope struct {
+	Code      errdefs.Code    `json:"code"`
+	Msg       string          `json:"msg"`
+	Data      json.RawMessage `json:"data"`
+	RequestID string          `json:"requestID"`
+	Partial   bool            `json:"partial"`
+	Stale     bool            `json:"stale"`
+}
+
+// query send the graphql query to the endpoint, the data of the response is decoded into dst.
+func (c *Client) query(ctx context.Context, endpoint, query string, dst interface{}) (Meta, error) {
+	u := c.baseURL + BasePath + endpoint + "?" + url.Values{"query": {query}}.Encode()
+
+	var (
+		env *envelope
+		err error
+	)
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		env, retryable, err = c.get(ctx, u)
+		if err == nil || !retryable || attempt >= c.retries {
+			break
+		}
+
+		timer := time.NewTimer(c.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Meta{}, err
+		case <-timer.C:
+		}
+	}
+	if err != nil {
+		return Meta{}, err
+	}
+
+	meta := Meta{
+		RequestID: env.RequestID,
+		Partial:   env.Partial,
+		Stale:     env.Stale,
+	}
+	if dst != nil && len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, dst); err != nil {
+			return meta, fmt.Errorf("search: decode data: %w", err)
+		}
+	}
+	return meta, nil
+}
+
+// get send the request, retryable is true if the request may succeed if retried.
+func (c *Client) get(ctx context.Context, u string) (_ *envelope, retryable bool, _ error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if tenantID := c.value(ctx, tenantKey, c.tenantID); tenantID != "" {
+		req.Header.Set(HeaderTenantID, tenantID)
+	}
+	if userID := c.value(ctx, userKey, c.userID); userID != "" {
+		req.Header.Set(HeaderUserID, userID)
+	}
+	if requestID := c.value(ctx, requestIDKey, ""); requestID != "" {
+		req.Header.Set(HeaderRequestID, requestID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		var netErr net.Error
+		return nil, errors.As(err, &netErr) || errors.Is(err, io.EOF), err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	env := &envelope{}
+	if err := json.Unmarshal(body, env); err != nil {
+		// not the envelope, e.g. the error page of a proxy.
+		return nil, retryStatus(resp.StatusCode), &Error{
+			StatusCode: resp.StatusCode,
+			Code:       errdefs.Unknown,
+			Message:    strings.TrimSpace(string(body)),
+			RequestID:  resp.Header.Get(HeaderRequestID),
+		}
+	}
+	if env.Code != errdefs.Success || resp.StatusCode >= http.StatusBadRequest {
+		return nil, retryStatus(resp.StatusCode), &Error{
+			StatusCode: resp.StatusCode,
+			Code:       env.Code,
+			Message:    env.Msg,
+			RequestID:  env.RequestID,
+		}
+	}
+	return env, false, nil
+}
+
+func (c *Client) value(ctx context.Context, key contextKey, def string) string {
+	if value, ok := ctx.Value(key).(string); ok {
+		return value
+	}
+	return def
+}
+
+// delay return the full jittered backoff before the retry of attempt.
+func (c *Client) delay(attempt int) time.Duration {
+	max := c.maxBackoff
+	if attempt < 30 {
+		if d := c.backoff << uint(attempt); d > 0 && d < max {
+			max = d
+		}
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+// retryStatus report whether the status means the server is unavailable,
+// the timeout is not retried as the query may be too heavy to answer in time.
+func retryStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
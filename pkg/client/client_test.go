@@ -0,0 +1,278 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quanxiang-cloud/search/api"
+	"github.com/quanxiang-cloud/search/internal/config"
+	"github.com/quanxiang-cloud/search/pkg/errdefs"
+)
+
+// newTestServer return the server running the real router on the memory
+// storage loaded with the fixtures of the service tests.
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	conf := config.Default()
+	conf.Storage.Backend = config.StorageMemory
+	conf.Storage.Memory.Fixtures = "../../internal/service/testdata/fixtures.json"
+
+	router, err := api.NewRouter(context.Background(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(router.Close)
+
+	handler := router.Handler()
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func userIDs(page *UserPage) []string {
+	ids := make([]string, 0, len(page.Users))
+	for _, user := range page.Users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTypedMethods(t *testing.T) {
+	server := newTestServer(t, nil)
+	c := New(server.URL, WithTenant("t1"))
+	ctx := context.Background()
+
+	page, err := c.SearchUsers(ctx, NewUserQuery().OrderBy("-createdAt"), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 4 || !equal(userIDs(page), []string{"u4", "u3"}) {
+		t.Errorf("search users: total %d, users %v", page.Total, userIDs(page))
+	}
+	if page.RequestID == "" {
+		t.Error("search users: request id is missing")
+	}
+
+	page, err = c.SearchUsers(ctx, NewUserQuery().Name("Alice"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(userIDs(page), []string{"u1"}) {
+		t.Fatalf("search users by name: %v", userIDs(page))
+	}
+	alice := page.Users[0]
+	if alice.Email != "alice@example.com" || alice.Gender != 2 || alice.CreatedAt != 100 ||
+		len(alice.Departments) != 1 || alice.Departments[0][0].ID != "d1" || alice.Departments[0][0].Attr != "1" ||
+		len(alice.Roles) != 1 || alice.Roles[0].ID != "r1" {
+		t.Errorf("search users by name: unexpected user %+v", alice)
+	}
+
+	users, err := c.UsersByIDs(ctx, "u2", "u3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Errorf("users by ids: %d users", len(users))
+	}
+
+	page, err = c.DepartmentMembers(ctx, "d2", NewUserQuery(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 {
+		t.Errorf("department members: total %d", page.Total)
+	}
+
+	page, err = c.RoleMembers(ctx, "r1", nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 {
+		t.Errorf("role members: total %d", page.Total)
+	}
+
+	page, err = c.Subordinates(ContextWithUser(ctx, "u1"), nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 {
+		t.Errorf("subordinates: total %d", page.Total)
+	}
+
+	leaders, err := c.Leaders(ContextWithUser(ctx, "u3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaders) != 2 {
+		t.Errorf("leaders: %d leaders", len(leaders))
+	}
+
+	deps, err := c.SearchDepartments(ctx, NewDepartmentQuery().Attr(1), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deps.Total != 1 || deps.Departments[0].ID != "d1" || deps.Departments[0].Attr != "1" {
+		t.Errorf("search departments: total %d", deps.Total)
+	}
+
+	byIDs, err := c.DepartmentsByIDs(ctx, "d2", "d3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byIDs) != 2 {
+		t.Errorf("departments by ids: %d departments", len(byIDs))
+	}
+}
+
+func TestTenantHeader(t *testing.T) {
+	server := newTestServer(t, nil)
+	c := New(server.URL, WithTenant("t1"))
+	ctx := context.Background()
+
+	page, err := c.SearchUsers(ContextWithTenant(ctx, "t2"), nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(userIDs(page), []string{"u5"}) {
+		t.Errorf("tenant of the context: %v", userIDs(page))
+	}
+
+	page, err = c.SearchUsers(ctx, NewUserQuery().Name("Alice"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(userIDs(page), []string{"u1"}) {
+		t.Errorf("tenant of the client: %v", userIDs(page))
+	}
+
+	page, err = c.SearchUsers(ContextWithRequestID(ctx, "r-1"), nil, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.RequestID != "r-1" {
+		t.Errorf("request id: %q", page.RequestID)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	server := newTestServer(t, nil)
+	c := New(server.URL, WithTenant("t1"))
+	ctx := context.Background()
+
+	var pages int32
+	it := NewUserIterator(3, func(ctx context.Context, page, size int) (*UserPage, error) {
+		atomic.AddInt32(&pages, 1)
+		return c.SearchUsers(ctx, NewUserQuery().OrderBy("createdAt"), page, size)
+	})
+	var ids []string
+	for {
+		user, err := it.Next(ctx)
+		if errors.Is(err, Done) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, user.ID)
+	}
+	if !equal(ids, []string{"u1", "u2", "u3", "u4"}) || pages != 2 {
+		t.Errorf("iterate users: %v in %d pages", ids, pages)
+	}
+	if _, err := it.Next(ctx); !errors.Is(err, Done) {
+		t.Errorf("iterate after done: %v", err)
+	}
+
+	deps := c.SearchDepartmentsIterator(nil, 2)
+	n := 0
+	for {
+		_, err := deps.Next(ctx)
+		if errors.Is(err, Done) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("iterate departments: %d departments", n)
+	}
+}
+
+func TestError(t *testing.T) {
+	server := newTestServer(t, nil)
+	c := New(server.URL, WithTenant("t1"))
+	ctx := context.Background()
+
+	_, err := c.Leaders(ContextWithUser(ctx, "unknown"))
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusNotFound || e.Code != errdefs.NotFound || e.RequestID == "" {
+		t.Fatalf("leaders of unknown user: %v", err)
+	}
+	var de *errdefs.Error
+	if !errors.As(err, &de) || de.Code != errdefs.NotFound {
+		t.Errorf("leaders of unknown user: not an errdefs error %v", err)
+	}
+
+	if _, err := c.SearchUsers(ctx, NewUserQuery().OrderBy("created At"), 0, 0); err == nil {
+		t.Error("invalid order by: expect error")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(HeaderTenantID) != "t1" {
+				t.Errorf("tenant header: %q", r.Header.Get(HeaderTenantID))
+			}
+			if atomic.AddInt32(&calls, 1) == 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx := context.Background()
+
+	c := New(server.URL, WithTenant("t1"), WithRetries(2, time.Millisecond))
+	if _, err := c.SearchUsers(ctx, nil, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("retry: %d calls", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	c = New(server.URL, WithTenant("t1"), WithRetries(0, 0))
+	_, err := c.SearchUsers(ctx, nil, 1, 1)
+	var e *Error
+	if !errors.As(err, &e) || e.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("no retry: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("no retry: %d calls", calls)
+	}
+}
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// selections of the queries, all fields of the objects.
+const (
+	userFields       = `id name phone email createdAt jobNumber avatar useStatus tenantID gender source selfEmail position departments{id name attr} roles{id name} leaders{id name attr}`
+	departmentFields = `id name attr pid tenantID`
+)
+
+var fieldName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// UserQuery builder of the filters of the users, the zero values are not sent.
+// Which filters are accepted depends on the endpoint, e.g. the role members
+// accept name, phone, email and departmentName, the others are rejected by the server.
+type UserQuery struct {
+	filter v1alpha1.SearchUser
+}
+
+// NewUserQuery return the empty query matching all users.
+func NewUserQuery() *UserQuery {
+	return &UserQuery{}
+}
+
+// UserQueryFrom return the query of the filter, TenantID and LeaderID are ignored,
+// the tenant is sent as the header and the leader is the user of the header.
+func UserQueryFrom(filter v1alpha1.SearchUser) *UserQuery {
+	return &UserQuery{filter: filter}
+}
+
+func (q *UserQuery) Name(name string) *UserQuery {
+	q.filter.Name = name
+	return q
+}
+
+func (q *UserQuery) Phone(phone string) *UserQuery {
+	q.filter.Phone = phone
+	return q
+}
+
+func (q *UserQuery) Email(email string) *UserQuery {
+	q.filter.Email = email
+	return q
+}
+
+func (q *UserQuery) JobNumber(jobNumber string) *UserQuery {
+	q.filter.JobNumber = jobNumber
+	return q
+}
+
+// Gender 1:man,2:woman
+func (q *UserQuery) Gender(gender int) *UserQuery {
+	q.filter.Gender = strconv.Itoa(gender)
+	return q
+}
+
+// UseStatus 1:normal,-2:disable,-1:del,2:active
+func (q *UserQuery) UseStatus(useStatus int) *UserQuery {
+	q.filter.UseStatus = useStatus
+	return q
+}
+
+func (q *UserQuery) DepartmentName(name string) *UserQuery {
+	q.filter.DepartmentName = name
+	return q
+}
+
+func (q *UserQuery) DepartmentID(departmentID string) *UserQuery {
+	q.filter.DepartmentID = departmentID
+	return q
+}
+
+func (q *UserQuery) RoleName(name string) *UserQuery {
+	q.filter.RoleName = name
+	return q
+}
+
+func (q *UserQuery) Position(position string) *UserQuery {
+	q.filter.Position = position
+	return q
+}
+
+// OrderBy append the sort fields, descending if prefixed by -, e.g. -createdAt.
+func (q *UserQuery) OrderBy(fields ...string) *UserQuery {
+	q.filter.OrderBy = append(q.filter.OrderBy, fields...)
+	return q
+}
+
+// Filter return the filters of the query.
+func (q *UserQuery) Filter() v1alpha1.SearchUser {
+	return q.filter
+}
+
+func (q *UserQuery) args() *args {
+	a := newArgs()
+	if q == nil {
+		return a
+	}
+	a.string("name", q.filter.Name)
+	a.string("phone", q.filter.Phone)
+	a.string("email", q.filter.Email)
+	a.string("jobNumber", q.filter.JobNumber)
+	a.string("gender", q.filter.Gender)
+	a.int("useStatus", q.filter.UseStatus)
+	a.string("departmentName", q.filter.DepartmentName)
+	a.string("departmentID", q.filter.DepartmentID)
+	a.string("roleID", q.filter.RoleID)
+	a.string("roleName", q.filter.RoleName)
+	a.string("position", q.filter.Position)
+	a.orderBy(q.filter.OrderBy)
+	return a
+}
+
+// DepartmentQuery builder of the filters of the departments, the zero values are not sent.
+type DepartmentQuery struct {
+	filter v1alpha1.SearchDepartment
+}
+
+// NewDepartmentQuery return the empty query matching all departments.
+func NewDepartmentQuery() *DepartmentQuery {
+	return &DepartmentQuery{}
+}
+
+// DepartmentQueryFrom return the query of the filter, TenantID and IDS are ignored,
+// use DepartmentsByIDs for the departments of the ids.
+func DepartmentQueryFrom(filter v1alpha1.SearchDepartment) *DepartmentQuery {
+	return &DepartmentQuery{filter: filter}
+}
+
+func (q *DepartmentQuery) Name(name string) *DepartmentQuery {
+	q.filter.Name = name
+	return q
+}
+
+// Attr append the attributes, e.g. 1 for the top-level departments.
+func (q *DepartmentQuery) Attr(attr ...int) *DepartmentQuery {
+	q.filter.Attr = append(q.filter.Attr, attr...)
+	return q
+}
+
+// OrderBy append the sort fields, descending if prefixed by -, e.g. -name.
+func (q *DepartmentQuery) OrderBy(fields ...string) *DepartmentQuery {
+	q.filter.OrderBy = append(q.filter.OrderBy, fields...)
+	return q
+}
+
+// Filter return the filters of the query.
+func (q *DepartmentQuery) Filter() v1alpha1.SearchDepartment {
+	return q.filter
+}
+
+func (q *DepartmentQuery) args() *args {
+	a := newArgs()
+	if q == nil {
+		return a
+	}
+	a.string("name", q.filter.Name)
+	if len(q.filter.Attr) > 0 {
+		attrs := make([]string, 0, len(q.filter.Attr))
+		for _, attr := range q.filter.Attr {
+			attrs = append(attrs, strconv.Itoa(attr))
+		}
+		a.values["attr"] = "[" + strings.Join(attrs, ",") + "]"
+	}
+	a.orderBy(q.filter.OrderBy)
+	return a
+}
+
+// args graphql arguments, the first invalid argument fails the render.
+type args struct {
+	// values name to literal
+	values map[string]string
+	err    error
+}
+
+func newArgs() *args {
+	return &args{values: make(map[string]string)}
+}
+
+func (a *args) string(name, value string) {
+	if value != "" {
+		a.values[name] = literal(value)
+	}
+}
+
+func (a *args) int(name string, value int) {
+	if value != 0 {
+		a.values[name] = strconv.Itoa(value)
+	}
+}
+
+func (a *args) strings(name string, values []string) {
+	literals := make([]string, 0, len(values))
+	for _, value := range values {
+		literals = append(literals, literal(value))
+	}
+	a.values[name] = "[" + strings.Join(literals, ",") + "]"
+}
+
+func (a *args) orderBy(fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	orders := make([]string, 0, len(fields))
+	for _, field := range fields {
+		order := "ASC"
+		if strings.HasPrefix(field, "-") {
+			field, order = field[1:], "DESC"
+		}
+		if !fieldName.MatchString(field) && a.err == nil {
+			a.err = fmt.Errorf("search: invalid order by field %q", field)
+		}
+		orders = append(orders, "{"+field+":"+literal(order)+"}")
+	}
+	a.values["orderBy"] = "[" + strings.Join(orders, ",") + "]"
+}
+
+func (a *args) page(page, size int) {
+	if page > 0 {
+		a.values["page"] = strconv.Itoa(page)
+	}
+	if size > 0 {
+		a.values["size"] = strconv.Itoa(size)
+	}
+}
+
+// render return the query of the root field with the arguments and the selection.
+func (a *args) render(selection string) (string, error) {
+	if a.err != nil {
+		return "", a.err
+	}
+	names := make([]string, 0, len(a.values))
+	for name := range a.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("{query")
+	if len(names) > 0 {
+		b.WriteString("(")
+		for i, name := range names {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(name + ":" + a.values[name])
+		}
+		b.WriteString(")")
+	}
+	b.WriteString("{" + selection + "}}")
+	return b.String(), nil
+}
+
+// literal return the graphql string literal of s, the json string is a valid one.
+func literal(s string) string {
+	body, _ := json.Marshal(s)
+	return string(body)
+}
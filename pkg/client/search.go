@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/quanxiang-cloud/search/pkg/apis/v1alpha1"
+)
+
+// UserPage users of a page.
+type UserPage struct {
+	Users []*v1alpha1.User
+	Total int64
+	Meta
+}
+
+// DepartmentPage departments of a page.
+type DepartmentPage struct {
+	Departments []*v1alpha1.Department
+	Total       int64
+	Meta
+}
+
+// SearchUsers search the users of the tenant, page starts from 1,
+// page and size are the defaults of the server if zero.
+func (c *Client) SearchUsers(ctx context.Context, q *UserQuery, page, size int) (*UserPage, error) {
+	a := q.args()
+	a.page(page, size)
+	return c.users(ctx, "/user", a)
+}
+
+// UsersByIDs list the users of ids.
+func (c *Client) UsersByIDs(ctx context.Context, ids ...string) ([]*v1alpha1.User, error) {
+	a := newArgs()
+	a.strings("ids", ids)
+	page, err := c.users(ctx, "/users", a)
+	if err != nil {
+		return nil, err
+	}
+	return page.Users, nil
+}
+
+// DepartmentMembers search the members of the department.
+func (c *Client) DepartmentMembers(ctx context.Context, departmentID string, q *UserQuery, page, size int) (*UserPage, error) {
+	a := q.args()
+	a.string("departmentID", departmentID)
+	a.page(page, size)
+	return c.users(ctx, "/department/member", a)
+}
+
+// Subordinates search the subordinates of the user, the user of ContextWithUser or WithUser.
+func (c *Client) Subordinates(ctx context.Context, q *UserQuery, page, size int) (*UserPage, error) {
+	a := q.args()
+	a.page(page, size)
+	return c.users(ctx, "/subordinate", a)
+}
+
+// Leaders list the leaders of the user, the user of ContextWithUser or WithUser,
+// from the recent leader to the top leader.
+func (c *Client) Leaders(ctx context.Context) ([]*v1alpha1.User, error) {
+	query, err := newArgs().render(userFields)
+	if err != nil {
+		return nil, err
+	}
+	var users []*user
+	if _, err := c.query(ctx, "/leader", query, &users); err != nil {
+		return nil, err
+	}
+	return toUsers(users), nil
+}
+
+// RoleMembers search the members of the role.
+func (c *Client) RoleMembers(ctx context.Context, roleID string, q *UserQuery, page, size int) (*UserPage, error) {
+	a := q.args()
+	a.string("roleID", roleID)
+	a.page(page, size)
+	return c.users(ctx, "/role/member", a)
+}
+
+// SearchDepartments search the departments of the tenant, page starts from 1,
+// page and size are the defaults of the server if zero.
+func (c *Client) SearchDepartments(ctx context.Context, q *DepartmentQuery, page, size int) (*DepartmentPage, error) {
+	a := q.args()
+	a.page(page, size)
+	return c.departments(ctx, "/department", a)
+}
+
+// DepartmentsByIDs list the departments of ids.
+func (c *Client) DepartmentsByIDs(ctx context.Context, ids ...string) ([]*v1alpha1.Department, error) {
+	a := newArgs()
+	a.strings("ids", ids)
+	page, err := c.departments(ctx, "/departments", a)
+	if err != nil {
+		return nil, err
+	}
+	return page.Departments, nil
+}
+
+func (c *Client) users(ctx context.Context, endpoint string, a *args) (*UserPage, error) {
+	query, err := a.render("total users{" + userFields + "}")
+	if err != nil {
+		return nil, err
+	}
+	data := struct {
+		Total int64   `json:"total"`
+		Users []*user `json:"users"`
+	}{}
+	meta, err := c.query(ctx, endpoint, query, &data)
+	if err != nil {
+		return nil, err
+	}
+	return &UserPage{
+		Users: toUsers(data.Users),
+		Total: data.Total,
+		Meta:  meta,
+	}, nil
+}
+
+func (c *Client) departments(ctx context.Context, endpoint string, a *args) (*DepartmentPage, error) {
+	query, err := a.render("total departments{" + departmentFields + "}")
+	if err != nil {
+		return nil, err
+	}
+	data := struct {
+		Total       int64         `json:"total"`
+		Departments []*department `json:"departments"`
+	}{}
+	meta, err := c.query(ctx, endpoint, query, &data)
+	if err != nil {
+		return nil, err
+	}
+	deps := make([]*v1alpha1.Department, 0, len(data.Departments))
+	for _, dep := range data.Departments {
+		d := dep.v1alpha1()
+		deps = append(deps, &d)
+	}
+	return &DepartmentPage{
+		Departments: deps,
+		Total:       data.Total,
+		Meta:        meta,
+	}, nil
+}
+
+// attr attribute answered as a number by graphql, a string in v1alpha1.
+type attr string
+
+func (a *attr) UnmarshalJSON(body []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(body, &n); err == nil {
+		*a = attr(n.String())
+		return nil
+	}
+	var s *string
+	if err := json.Unmarshal(body, &s); err != nil {
+		return err
+	}
+	if s != nil {
+		*a = attr(*s)
+	}
+	return nil
+}
+
+// department wire form of v1alpha1.Department.
+type department struct {
+	v1alpha1.Department
+	Attr attr `json:"attr"`
+}
+
+func (d *department) v1alpha1() v1alpha1.Department {
+	dep := d.Department
+	dep.Attr = string(d.Attr)
+	return dep
+}
+
+// user wire form of v1alpha1.User.
+type user struct {
+	v1alpha1.User
+	Departments [][]department `json:"departments"`
+}
+
+func toUsers(users []*user) []*v1alpha1.User {
+	result := make([]*v1alpha1.User, 0, len(users))
+	for _, u := range users {
+		user := u.User
+		user.Departments = make([][]v1alpha1.Department, 0, len(u.Departments))
+		for _, path := range u.Departments {
+			deps := make([]v1alpha1.Department, 0, len(path))
+			for _, dep := range path {
+				deps = append(deps, dep.v1alpha1())
+			}
+			user.Departments = append(user.Departments, deps)
+		}
+		result = append(result, &user)
+	}
+	return result
+}
+
+// Done returned by the iterators after the last item.
+var Done = errors.New("no more items")
+
+// UserIterator iterate the users page by page.
+type UserIterator struct {
+	fetch func(ctx context.Context, page, size int) (*UserPage, error)
+	size  int
+	page  int
+	buf   []*v1alpha1.User
+	seen  int64
+	last  bool
+}
+
+// NewUserIterator return the iterator of the pages of size fetched by fetch, e.g.
+//
+//	it := client.NewUserIterator(100, func(ctx context.Context, page, size int) (*client.UserPage, error) {
+//		return c.RoleMembers(ctx, "r1", nil, page, size)
+//	})
+func NewUserIterator(size int, fetch func(ctx context.Context, page, size int) (*UserPage, error)) *UserIterator {
+	return &UserIterator{fetch: fetch, size: pageSize(size)}
+}
+
+// SearchUsersIterator return the iterator of the users of the query.
+func (c *Client) SearchUsersIterator(q *UserQuery, size int) *UserIterator {
+	return NewUserIterator(size, func(ctx context.Context, page, size int) (*UserPage, error) {
+		return c.SearchUsers(ctx, q, page, size)
+	})
+}
+
+// Next return the next user, Done after the last user.
+func (it *UserIterator) Next(ctx context.Context) (*v1alpha1.User, error) {
+	for len(it.buf) == 0 {
+		if it.last {
+			return nil, Done
+		}
+		it.page++
+		page, err := it.fetch(ctx, it.page, it.size)
+		if err != nil {
+			it.page--
+			return nil, err
+		}
+		it.buf = page.Users
+		it.seen += int64(len(page.Users))
+		it.last = len(page.Users) < it.size || it.seen >= page.Total
+	}
+	user := it.buf[0]
+	it.buf = it.buf[1:]
+	return user, nil
+}
+
+// DepartmentIterator iterate the departments page by page.
+type DepartmentIterator struct {
+	fetch func(ctx context.Context, page, size int) (*DepartmentPage, error)
+	size  int
+	page  int
+	buf   []*v1alpha1.Department
+	seen  int64
+	last  bool
+}
+
+// NewDepartmentIterator return the iterator of the pages of size fetched by fetch.
+func NewDepartmentIterator(size int, fetch func(ctx context.Context, page, size int) (*DepartmentPage, error)) *DepartmentIterator {
+	return &DepartmentIterator{fetch: fetch, size: pageSize(size)}
+}
+
+// SearchDepartmentsIterator return the iterator of the departments of the query.
+func (c *Client) SearchDepartmentsIterator(q *DepartmentQuery, size int) *DepartmentIterator {
+	return NewDepartmentIterator(size, func(ctx context.Context, page, size int) (*DepartmentPage, error) {
+		return c.SearchDepartments(ctx, q, page, size)
+	})
+}
+
+// Next return the next department, Done after the last department.
+func (it *DepartmentIterator) Next(ctx context.Context) (*v1alpha1.Department, error) {
+	for len(it.buf) == 0 {
+		if it.last {
+			return nil, Done
+		}
+		it.page++
+		page, err := it.fetch(ctx, it.page, it.size)
+		if err != nil {
+			it.page--
+			return nil, err
+		}
+		it.buf = page.Departments
+		it.seen += int64(len(page.Departments))
+		it.last = len(page.Departments) < it.size || it.seen >= page.Total
+	}
+	dep := it.buf[0]
+	it.buf = it.buf[1:]
+	return dep, nil
+}
+
+// pageSize return the page size of the iterators, default 100.
+func pageSize(size int) int {
+	if size <= 0 {
+		return 100
+	}
+	return size
+}
@@ -0,0 +1,184 @@
+package errdefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Code error code of the response envelope.
+type Code int64
+
+// error codes, Unknown, Internal, Success and InvalidArgument
+// keep the same value as cabin.
+const (
+	Unknown         Code = -1
+	Internal        Code = -2
+	Success         Code = 0
+	InvalidArgument Code = 1
+	NotFound        Code = 2
+	Unauthorized    Code = 3
+	Unavailable     Code = 4
+	Timeout         Code = 5
+)
+
+var codes = map[Code]struct {
+	name   string
+	status int
+}{
+	Unknown:         {"UNKNOWN", http.StatusInternalServerError},
+	Internal:        {"INTERNAL", http.StatusInternalServerError},
+	Success:         {"SUCCESS", http.StatusOK},
+	InvalidArgument: {"INVALID_ARGUMENT", http.StatusBadRequest},
+	NotFound:        {"NOT_FOUND", http.StatusNotFound},
+	Unauthorized:    {"UNAUTHORIZED", http.StatusUnauthorized},
+	Unavailable:     {"UNAVAILABLE", http.StatusServiceUnavailable},
+	Timeout:         {"TIMEOUT", http.StatusGatewayTimeout},
+}
+
+// String return the name of the code, used as the extensions.code of graphql errors
+// unless overridden by Error.ExtensionCode.
+func (c Code) String() string {
+	if v, ok := codes[c]; ok {
+		return v.name
+	}
+	return codes[Unknown].name
+}
+
+// HTTPStatus return the http status of the code.
+func (c Code) HTTPStatus() int {
+	if v, ok := codes[c]; ok {
+		return v.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error error with code.
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"msg,omitempty"`
+	// ExtensionCode overrides the extensions.code of the graphql error,
+	// e.g. the codes of the Automatic Persisted Queries protocol.
+	ExtensionCode string `json:"-"`
+
+	cause error
+}
+
+// New return error with code and message.
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Wrap return error with code, caused by err.
+func Wrap(code Code, err error) *Error {
+	return &Error{
+		Code:    code,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+// WithExtensionCode set the extensions.code of the error.
+func (e *Error) WithExtensionCode(code string) *Error {
+	e.ExtensionCode = code
+	return e
+}
+
+// Error return error string
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Code.String()
+}
+
+// Unwrap return the cause.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *Error) Extensions() map[string]interface{} {
+	code := e.Code.String()
+	if e.ExtensionCode != "" {
+		code = e.ExtensionCode
+	}
+	return map[string]interface{}{
+		"code": code,
+	}
+}
+
+// NewInvalidArgument return invalid argument error.
+func NewInvalidArgument(format string, args ...interface{}) *Error {
+	return New(InvalidArgument, format, args...)
+}
+
+// NewNotFound return not found error.
+func NewNotFound(format string, args ...interface{}) *Error {
+	return New(NotFound, format, args...)
+}
+
+// NewUnauthorized return unauthorized error.
+func NewUnauthorized(format string, args ...interface{}) *Error {
+	return New(Unauthorized, format, args...)
+}
+
+// NewUnavailable return upstream unavailable error.
+func NewUnavailable(format string, args ...interface{}) *Error {
+	return New(Unavailable, format, args...)
+}
+
+// NewTimeout return timeout error.
+func NewTimeout(format string, args ...interface{}) *Error {
+	return New(Timeout, format, args...)
+}
+
+// From return the *Error of err, errors without code are internal errors,
+// except graphql syntax and validation errors which are invalid arguments.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	switch ge := err.(type) {
+	case gqlerrors.FormattedError:
+		if origin := ge.OriginalError(); origin != nil {
+			return From(origin)
+		}
+		return &Error{Code: InvalidArgument, Message: ge.Message, cause: err}
+	case *gqlerrors.Error:
+		if ge.OriginalError != nil {
+			return From(ge.OriginalError)
+		}
+		// syntax and validation errors
+		return &Error{Code: InvalidArgument, Message: ge.Message, cause: err}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Wrap(Timeout, err)
+	}
+	if errors.Is(err, context.Canceled) {
+		return Wrap(Unavailable, err)
+	}
+
+	return Wrap(Internal, err)
+}
+
+// HTTPStatus return the http status of err.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return From(err).Code.HTTPStatus()
+}
@@ -0,0 +1,147 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Checker health checker evaluated periodically by the probe,
+// any failing checker makes the readiness probe fail unless it is advisory.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checker struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// NewChecker return Checker with name and check function.
+func NewChecker(name string, check func(ctx context.Context) error) Checker {
+	return &checker{
+		name:  name,
+		check: check,
+	}
+}
+
+func (c *checker) Name() string {
+	return c.name
+}
+
+func (c *checker) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+type advisoryChecker struct {
+	Checker
+	advisory func() bool
+}
+
+// Advisory return the checker whose failure is reported by the readiness body
+// without failing the probe while advisory returns true, e.g. the results
+// can still be served while the storage is down.
+func Advisory(c Checker, advisory func() bool) Checker {
+	return &advisoryChecker{
+		Checker:  c,
+		advisory: advisory,
+	}
+}
+
+// isAdvisory report whether the failure of c fails the probe.
+func isAdvisory(c Checker) bool {
+	a, ok := c.(*advisoryChecker)
+	return ok && a.advisory()
+}
+
+// checks results of the checkers.
+type checks struct {
+	mu sync.RWMutex
+	// evaluated false until the first evaluation finished
+	evaluated bool
+	// failures failing checks, including the advisory ones
+	failures map[string]string
+	// failed number of the failing checks which are not advisory
+	failed int
+}
+
+func (c *checks) set(failures map[string]string, failed int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evaluated = true
+	c.failures = failures
+	c.failed = failed
+}
+
+// get return the failing checks, ok is false if any check failed but the
+// advisory ones, or the checks have not been evaluated.
+func (c *checks) get() (map[string]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.failures, c.evaluated && c.failed == 0
+}
+
+// AddChecker add checkers, must be called before Start.
+func (p *Probe) AddChecker(checkers ...Checker) {
+	p.checkers = append(p.checkers, checkers...)
+}
+
+// Start evaluate the checkers every interval until ctx done,
+// each checker is canceled after timeout.
+func (p *Probe) Start(ctx context.Context, interval, timeout time.Duration) {
+	p.evaluate(ctx, timeout)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.evaluate(ctx, timeout)
+			}
+		}
+	}()
+}
+
+func (p *Probe) evaluate(ctx context.Context, timeout time.Duration) {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures = make(map[string]string)
+		failed   int
+	)
+	for _, c := range p.checkers {
+		wg.Add(1)
+		go func(c Checker) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			if err := c.Check(ctx); err != nil {
+				mu.Lock()
+				failures[c.Name()] = err.Error()
+				if !isAdvisory(c) {
+					failed++
+				}
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	prev, _ := p.checks.get()
+	for name, reason := range failures {
+		if _, ok := prev[name]; !ok {
+			p.log.Info("health check failed", "checker", name, "reason", reason)
+		}
+	}
+	for name := range prev {
+		if _, ok := failures[name]; !ok {
+			p.log.Info("health check recovered", "checker", name)
+		}
+	}
+	p.checks.set(failures, failed)
+}
@@ -0,0 +1,89 @@
+package probe
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestStart(t *testing.T) {
+	var calls int32
+	p := New(logr.Discard())
+	p.AddChecker(NewChecker("count", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Start(ctx, 20*time.Millisecond, time.Second)
+	// the checkers are evaluated once before Start returns
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("calls after start %d, expect 1", n)
+	}
+	if _, ok := p.checks.get(); !ok {
+		t.Error("checks are not evaluated after start")
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	cancel()
+	n := atomic.LoadInt32(&calls)
+	if n < 3 || n > 7 {
+		t.Errorf("calls in 5 intervals %d", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if stopped := atomic.LoadInt32(&calls); stopped > n+1 {
+		t.Errorf("calls after cancel %d, expect at most %d", stopped, n+1)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	p := New(logr.Discard())
+	p.AddChecker(NewChecker("slow", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}), NewChecker("fast", func(ctx context.Context) error {
+		return nil
+	}))
+
+	start := time.Now()
+	p.evaluate(context.Background(), 20*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("evaluate took %s, the checker is not canceled by the timeout", elapsed)
+	}
+	failures, ok := p.checks.get()
+	if ok || failures["slow"] != context.DeadlineExceeded.Error() {
+		t.Errorf("failures %v", failures)
+	}
+	if _, failed := failures["fast"]; failed {
+		t.Errorf("fast checker failed: %v", failures)
+	}
+}
+
+func TestRecovered(t *testing.T) {
+	var down int32 = 1
+	p := New(logr.Discard())
+	p.AddChecker(NewChecker("flaky", func(ctx context.Context) error {
+		if atomic.LoadInt32(&down) == 1 {
+			return context.DeadlineExceeded
+		}
+		return nil
+	}))
+
+	p.evaluate(context.Background(), time.Second)
+	if _, ok := p.checks.get(); ok {
+		t.Error("failing checker passed")
+	}
+	atomic.StoreInt32(&down, 0)
+	p.evaluate(context.Background(), time.Second)
+	if failures, ok := p.checks.get(); !ok || len(failures) != 0 {
+		t.Errorf("recovered checker: %v", failures)
+	}
+}
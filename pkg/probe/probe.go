@@ -1,8 +1,10 @@
 package probe
 
 import (
+	"encoding/json"
 	"net/http"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/go-logr/logr"
@@ -12,12 +14,21 @@ const (
 	readinessPending int32 = iota
 	readinessTrue
 	readinessFalse
+	// readinessDraining not ready but still alive,
+	// in-flight requests are draining.
+	readinessDraining
 )
 
 // Probe probe
 type Probe struct {
 	readiness int32
 
+	checkers []Checker
+	checks   checks
+
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+
 	log logr.Logger
 }
 
@@ -26,6 +37,7 @@ func New(log logr.Logger) *Probe {
 	return &Probe{
 		log:       log,
 		readiness: readinessPending,
+		shutdown:  make(chan struct{}),
 	}
 }
 
@@ -47,6 +59,18 @@ func (p *Probe) SetRunning() {
 	p.setTrue()
 }
 
+// SetDraining set not ready and keep alive,
+// new requests are no longer routed to the instance.
+func (p *Probe) SetDraining() {
+	p.log.Info("probe draining")
+	atomic.StoreInt32(&p.readiness, readinessDraining)
+}
+
+// Shutdown return a channel closed when the shutdown is requested by the readiness probe.
+func (p *Probe) Shutdown() <-chan struct{} {
+	return p.shutdown
+}
+
 // LivenessProbe liveness probe
 func (p *Probe) LivenessProbe(w http.ResponseWriter, r *http.Request) {
 	if p.getReadiness() != readinessFalse {
@@ -65,7 +89,8 @@ func (p *Probe) isSafe(r *http.Request) bool {
 	return false
 }
 
-// ReadinessProbe readiness probe
+// ReadinessProbe readiness probe, the failing checks are listed by the body,
+// the advisory ones are listed without failing the probe.
 func (p *Probe) ReadinessProbe(w http.ResponseWriter, r *http.Request) {
 	if r.Header.Get("x-readiness-shutdown") != "" {
 		if !p.isSafe(r) {
@@ -74,14 +99,40 @@ func (p *Probe) ReadinessProbe(w http.ResponseWriter, r *http.Request) {
 		}
 		p.log.Info("readiness shutdown")
 		p.setFalse()
+		p.shutdownOnce.Do(func() {
+			close(p.shutdown)
+		})
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if p.getReadiness() != readinessTrue {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if p.getReadiness() == readinessTrue {
+	failures, ok := p.checks.get()
+	if len(p.checkers) == 0 || (ok && len(failures) == 0) {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	w.WriteHeader(http.StatusBadRequest)
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusBadRequest
+	}
+	if failures == nil {
+		failures = map[string]string{
+			"probe": "health checks have not been evaluated",
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Ready  bool              `json:"ready"`
+		Checks map[string]string `json:"checks"`
+	}{
+		Ready:  ok,
+		Checks: failures,
+	})
 }
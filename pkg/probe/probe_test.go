@@ -0,0 +1,112 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+type readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// ready return the status and the body of the readiness probe.
+func ready(t *testing.T, p *Probe) (int, *readiness) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	p.ReadinessProbe(w, httptest.NewRequest(http.MethodGet, "/readiness", nil))
+	if w.Body.Len() == 0 {
+		return w.Code, nil
+	}
+	body := &readiness{}
+	if err := json.Unmarshal(w.Body.Bytes(), body); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, body
+}
+
+func failing(name string) Checker {
+	return NewChecker(name, func(ctx context.Context) error {
+		return errors.New(name + " is down")
+	})
+}
+
+func TestAdvisory(t *testing.T) {
+	advisory := true
+	p := New(logr.Discard())
+	p.AddChecker(Advisory(failing("storage"), func() bool { return advisory }))
+	p.SetRunning()
+
+	p.evaluate(context.Background(), time.Second)
+	code, body := ready(t, p)
+	if code != http.StatusOK || body == nil || !body.Ready || body.Checks["storage"] != "storage is down" {
+		t.Errorf("advisory failure: status %d, body %+v", code, body)
+	}
+
+	advisory = false
+	p.evaluate(context.Background(), time.Second)
+	code, body = ready(t, p)
+	if code != http.StatusBadRequest || body == nil || body.Ready || body.Checks["storage"] != "storage is down" {
+		t.Errorf("failure: status %d, body %+v", code, body)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	p := New(logr.Discard())
+	p.AddChecker(failing("elasticsearch"), NewChecker("sql", func(ctx context.Context) error {
+		return nil
+	}))
+
+	if code, _ := ready(t, p); code != http.StatusBadRequest {
+		t.Errorf("pending: status %d", code)
+	}
+
+	p.SetRunning()
+	code, body := ready(t, p)
+	if code != http.StatusBadRequest || body == nil || body.Ready || body.Checks["probe"] == "" {
+		t.Errorf("not evaluated: status %d, body %+v", code, body)
+	}
+
+	p.evaluate(context.Background(), time.Second)
+	code, body = ready(t, p)
+	if code != http.StatusBadRequest || body == nil || body.Ready ||
+		len(body.Checks) != 1 || body.Checks["elasticsearch"] != "elasticsearch is down" {
+		t.Errorf("failing: status %d, body %+v", code, body)
+	}
+
+	p.SetDraining()
+	if code, _ := ready(t, p); code != http.StatusBadRequest {
+		t.Errorf("draining: status %d", code)
+	}
+	w := httptest.NewRecorder()
+	p.LivenessProbe(w, httptest.NewRequest(http.MethodGet, "/liveness", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("draining liveness: status %d", w.Code)
+	}
+}
+
+func TestReadinessPassed(t *testing.T) {
+	p := New(logr.Discard())
+	p.AddChecker(NewChecker("sql", func(ctx context.Context) error {
+		return nil
+	}))
+	p.SetRunning()
+	p.evaluate(context.Background(), time.Second)
+	if code, body := ready(t, p); code != http.StatusOK || body != nil {
+		t.Errorf("passed: status %d, body %+v", code, body)
+	}
+
+	// without checkers the readiness is decided by the running state only
+	p = New(logr.Discard())
+	p.SetRunning()
+	if code, _ := ready(t, p); code != http.StatusOK {
+		t.Errorf("without checkers: status %d", code)
+	}
+}
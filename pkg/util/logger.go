@@ -3,28 +3,138 @@ package util
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type ContextKey struct{}
 
+type requestKey struct{}
+
 func SetCtx(ctx context.Context, key, value interface{}) context.Context {
 	return context.WithValue(ctx, key, value)
 }
 
+// defaultLogger the logger of the contexts carrying no logger,
+// guarded by defaultMu, it is set by SetDefaultLogger or on first use.
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger logr.Logger
+	defaultSet    bool
+)
+
+// SetDefaultLogger set the logger returned by LoggerFromContext
+// if the context carries no logger.
+func SetDefaultLogger(log logr.Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger, defaultSet = log, true
+}
+
 func LoggerFromContext(ctx context.Context) logr.Logger {
-	log, ok := ctx.Value(ContextKey{}).(logr.Logger)
-	if !ok {
+	if log, ok := ctx.Value(ContextKey{}).(logr.Logger); ok {
+		return log
+	}
+
+	defaultMu.RLock()
+	log, ok := defaultLogger, defaultSet
+	defaultMu.RUnlock()
+	if ok {
+		return log
+	}
+
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if !defaultSet {
 		zapLog, err := zap.NewDevelopment()
 		if err != nil {
 			panic(fmt.Sprintf("who watches the watchmen (%v)?", err))
 		}
-		log = zapr.NewLogger(zapLog)
-		log.Error(fmt.Errorf("the log processor has not been initialized"), "context")
+		defaultLogger, defaultSet = zapr.NewLogger(zapLog), true
+		defaultLogger.Error(fmt.Errorf("the log processor has not been initialized"), "context")
 	}
+	return defaultLogger
+}
+
+// SetRequestValues carry the key values of the request, e.g. request id, tenant and user,
+// which are attached to the loggers by LoggerWithRequest.
+func SetRequestValues(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return context.WithValue(ctx, requestKey{}, keysAndValues)
+}
 
+// LoggerWithRequest return log with the key values of the request carried by ctx.
+func LoggerWithRequest(ctx context.Context, log logr.Logger) logr.Logger {
+	if kv, ok := ctx.Value(requestKey{}).([]interface{}); ok {
+		return log.WithValues(kv...)
+	}
 	return log
 }
+
+// LogConfig logging config
+type LogConfig struct {
+	// Level debug, info, warn or error, default info
+	Level string `yaml:"level"`
+	// Encoding json or console, default json
+	Encoding string `yaml:"encoding"`
+	// Development stack traces on warnings and the development encoder config
+	Development bool `yaml:"development"`
+	// Sampling nil means no sampling
+	Sampling *LogSampling `yaml:"sampling"`
+	// SlowQuery queries slower than it are logged, zero means never
+	SlowQuery time.Duration `yaml:"slowQuery"`
+}
+
+// LogSampling log the first Initial entries with the same level and message
+// each second, and every Thereafter entry after that.
+type LogSampling struct {
+	Initial    int `yaml:"initial"`
+	Thereafter int `yaml:"thereafter"`
+}
+
+// logLevel level of the loggers returned by NewLogger, changed by SetLogLevel.
+var logLevel = zap.NewAtomicLevel()
+
+// SetLogLevel change the level of the loggers returned by NewLogger.
+func SetLogLevel(level string) error {
+	if level == "" {
+		level = zapcore.InfoLevel.String()
+	}
+	return logLevel.UnmarshalText([]byte(level))
+}
+
+// NewLogger return zap logger from the config.
+func NewLogger(conf LogConfig) (*zap.Logger, error) {
+	zapConf := zap.NewProductionConfig()
+	if conf.Development {
+		zapConf = zap.NewDevelopmentConfig()
+	}
+	zapConf.Sampling = nil
+	if conf.Sampling != nil {
+		zapConf.Sampling = &zap.SamplingConfig{
+			Initial:    conf.Sampling.Initial,
+			Thereafter: conf.Sampling.Thereafter,
+		}
+	}
+
+	if err := SetLogLevel(conf.Level); err != nil {
+		return nil, err
+	}
+	zapConf.Level = logLevel
+
+	switch conf.Encoding {
+	case "":
+		zapConf.Encoding = "json"
+	case "json", "console":
+		zapConf.Encoding = conf.Encoding
+	default:
+		return nil, fmt.Errorf("unknown log encoding %q", conf.Encoding)
+	}
+	zapConf.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	return zapConf.Build()
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+// TestDefaultLogger set the default logger while it is read, run with -race.
+func TestDefaultLogger(t *testing.T) {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetDefaultLogger(logr.Discard())
+		}()
+		go func() {
+			defer wg.Done()
+			LoggerFromContext(ctx).Info("default")
+		}()
+	}
+	wg.Wait()
+
+	log := logr.Discard().WithName("set")
+	SetDefaultLogger(log)
+	if got := LoggerFromContext(ctx); got != log {
+		t.Error("default logger is not the one set")
+	}
+	carried := logr.Discard().WithName("carried")
+	if got := LoggerFromContext(SetCtx(ctx, ContextKey{}, carried)); got != carried {
+		t.Error("logger of the context is not preferred")
+	}
+}